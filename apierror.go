@@ -0,0 +1,45 @@
+package logshare
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// ErrLogsNotEnabled is returned when the API reports that the zone is not
+// entitled to retrieve logs. Use errors.Cause to check for it.
+var ErrLogsNotEnabled = errors.New("logs are not enabled for this zone: Log Share (Logpull) requires an Enterprise plan, and must be enabled for the zone after upgrading")
+
+// apiErrorResponse is the error envelope returned by the Cloudflare API.
+type apiErrorResponse struct {
+	Errors []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// codeLogsNotEnabled is the API error code for a zone that isn't entitled to
+// logs.
+const codeLogsNotEnabled = 1002
+
+// logsNotEnabled reports whether an error response indicates that the zone
+// isn't entitled to logs: a 400 or 403 response with the error code for it.
+func logsNotEnabled(statusCode int, body []byte) bool {
+	if statusCode != http.StatusBadRequest && statusCode != http.StatusForbidden {
+		return false
+	}
+
+	var resp apiErrorResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return false
+	}
+
+	for _, e := range resp.Errors {
+		if e.Code == codeLogsNotEnabled {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,51 @@
+package logshare
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestLogsNotEnabled(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   bool
+	}{
+		{name: "forbidden", status: http.StatusForbidden, body: `{"success":false,"errors":[{"code":1002,"message":"Forbidden"}]}`, want: true},
+		{name: "bad request", status: http.StatusBadRequest, body: `{"success":false,"errors":[{"code":1002,"message":"Bad request"}]}`, want: true},
+		{name: "among other errors", status: http.StatusForbidden, body: `{"errors":[{"code":10000,"message":"Authentication error"},{"code":1002,"message":""}]}`, want: true},
+		{name: "other code mentioning logs", status: http.StatusForbidden, body: `{"errors":[{"code":10000,"message":"logs not allowed for this token"}]}`},
+		{name: "other status", status: http.StatusInternalServerError, body: `{"errors":[{"code":1002,"message":""}]}`},
+		{name: "not JSON", status: http.StatusForbidden, body: `forbidden`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := logsNotEnabled(tt.status, []byte(tt.body)); got != tt.want {
+				t.Errorf("logsNotEnabled = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrLogsNotEnabled(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"success":false,"errors":[{"code":1002,"message":"Forbidden"}]}`))
+	})
+	c, srv := newTestClient(t, handler, nil)
+	defer srv.Close()
+
+	start := time.Now().Add(-time.Hour).Unix()
+	meta, err := c.GetFromTimestamp(testZoneID, start, start+60, 0)
+	if errors.Cause(err) != ErrLogsNotEnabled {
+		t.Fatalf("err = %v, want ErrLogsNotEnabled", err)
+	}
+	if meta == nil || meta.StatusCode != http.StatusForbidden {
+		t.Errorf("Meta = %+v, want the 403 status", meta)
+	}
+}
@@ -0,0 +1,45 @@
+package logshare
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// AuthType is a scheme for authenticating with the API.
+type AuthType string
+
+const (
+	// AuthAPIToken authenticates with an API token, as a bearer token.
+	AuthAPIToken AuthType = "token"
+	// AuthAPIKey authenticates with a legacy API key and email address.
+	AuthAPIKey AuthType = "key"
+)
+
+// defaultAuthPreference is the order schemes are chosen in when no
+// Options.AuthPreference is set.
+var defaultAuthPreference = []AuthType{AuthAPIToken, AuthAPIKey}
+
+// chooseAuth returns the first scheme in preference that the client has
+// credentials for.
+func (c *Client) chooseAuth(preference []AuthType) (AuthType, error) {
+	var missing []string
+	for _, a := range preference {
+		switch a {
+		case AuthAPIToken:
+			if c.apiToken != "" {
+				return a, nil
+			}
+			missing = append(missing, "token (apiToken is empty)")
+		case AuthAPIKey:
+			if c.apiKey != "" && c.apiEmail != "" {
+				return a, nil
+			}
+			missing = append(missing, "key (apiKey or apiEmail is empty)")
+		default:
+			return "", errors.Errorf("unknown AuthType %q", a)
+		}
+	}
+
+	return "", errors.Errorf("no credentials for the preferred auth schemes: %s", strings.Join(missing, ", "))
+}
@@ -0,0 +1,266 @@
+package logshare
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Default BackfillOptions.
+const (
+	DefaultChunkSize          = time.Hour
+	DefaultBackfillWorkers    = 4
+	DefaultChunkRetryBackoff  = time.Second
+	defaultChunkRetryAttempts = 3
+)
+
+// TimeRange is a window of time, from Start (inclusive) to End (exclusive).
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (t TimeRange) String() string {
+	return fmt.Sprintf("%s-%s", t.Start.UTC().Format(time.RFC3339), t.End.UTC().Format(time.RFC3339))
+}
+
+// within reports whether t lies entirely within any of the given ranges.
+func (t TimeRange) within(ranges []TimeRange) bool {
+	for _, r := range ranges {
+		if !t.Start.Before(r.Start) && !t.End.After(r.End) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// BackfillOptions configures ConcurrentBackfill.
+type BackfillOptions struct {
+	// The size of each chunk. Defaults to DefaultChunkSize.
+	ChunkSize time.Duration
+	// The number of chunks fetched at once. Defaults to
+	// DefaultBackfillWorkers.
+	Concurrency int
+	// The number of times a failed chunk is retried. Defaults to 3; set a
+	// negative value to disable retries.
+	MaxRetries int
+	// The delay before a chunk's first retry, doubled after each attempt.
+	// Defaults to DefaultChunkRetryBackoff.
+	RetryBackoff time.Duration
+	// The total number of retries allowed across all chunks. Once used up, no
+	// new chunks are started and ErrRetryBudgetExhausted is returned. Zero
+	// means no limit.
+	RetryBudget int
+
+	// Resume a backfill by skipping chunks that were already fetched: every
+	// chunk before index StartChunk (counting from zero), and every chunk
+	// that lies entirely within one of SkipWindows. Skipped and fetched
+	// chunks are listed in Meta.SkippedWindows and Meta.FetchedWindows.
+	StartChunk  int
+	SkipWindows []TimeRange
+
+	// Open a connection for each worker before the first chunks are
+	// fetched (see WarmConnections). A failure to warm is not an error:
+	// the chunks connect as they would have otherwise.
+	WarmConnections bool
+}
+
+// ConcurrentBackfill fetches the logs between start and end in chunks of
+// ChunkSize, several at a time, and writes them to the client's destination.
+//
+// Each chunk is buffered in memory and written to the destination as a whole
+// once it succeeds, so that a retried chunk never writes duplicate logs. With
+// Options.Sequence set, every chunk draws from one sequence, so numbers are
+// unique across the backfill (though a retried chunk leaves gaps).
+// Chunks complete out of order, so their logs are not written in time order;
+// FormatNDJSON is recommended as the CSV header and JSON array brackets are
+// written once per chunk.
+//
+// The returned Meta summarizes every chunk. On the first chunk to fail, no
+// further chunks are started and the error is returned once the chunks in
+// flight have finished.
+//
+// Options.MaxWallClock bounds the backfill as a whole. Once it is reached, no
+// further chunks are started, and the chunks in flight are discarded rather
+// than written in part; Meta.Partial is set, and Meta.FetchedWindows lists the
+// chunks that were written, to resume from.
+func (c *Client) ConcurrentBackfill(ctx context.Context, zoneID string, start time.Time, end time.Time, opts *BackfillOptions) (*Meta, error) {
+	o := BackfillOptions{}
+	if opts != nil {
+		o = *opts
+	}
+
+	if o.ChunkSize <= 0 {
+		o.ChunkSize = DefaultChunkSize
+	}
+
+	if o.Concurrency <= 0 {
+		o.Concurrency = DefaultBackfillWorkers
+	}
+
+	if o.MaxRetries == 0 {
+		o.MaxRetries = defaultChunkRetryAttempts
+	}
+
+	if o.RetryBackoff <= 0 {
+		o.RetryBackoff = DefaultChunkRetryBackoff
+	}
+
+	if !end.After(start) {
+		return nil, errors.New("end must be after start")
+	}
+
+	ctx, cancel := c.withWallClock(ctx)
+	defer cancel()
+
+	if o.WarmConnections {
+		c.WarmConnections(ctx, o.Concurrency)
+	}
+
+	budget := newRetryBudget(o.RetryBudget)
+	seq := c.newSequencer()
+	total := &Meta{}
+
+	var (
+		mu       sync.Mutex
+		destMu   sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+
+	work := make(chan TimeRange)
+	for i := 0; i < o.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for w := range work {
+				meta, err := c.backfillChunk(ctx, zoneID, w, &o, budget, seq, &destMu)
+
+				mu.Lock()
+				total.Chunks++
+				c.metrics.IncChunks()
+				switch {
+				case err == nil && meta.Partial:
+					// The chunk was not written, so it is left for a resumed
+					// backfill.
+					total.Partial = true
+				case err == nil:
+					total.add(meta)
+					total.FetchedWindows = append(total.FetchedWindows, w)
+				default:
+					total.add(meta)
+					if firstErr == nil {
+						firstErr = errors.Wrapf(err, "chunk %s", w)
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for i, w := range splitWindows(start, end, o.ChunkSize) {
+		if i < o.StartChunk || w.within(o.SkipWindows) {
+			mu.Lock()
+			total.SkippedWindows = append(total.SkippedWindows, w)
+			mu.Unlock()
+			continue
+		}
+
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+
+		if failed || budget.isExhausted() {
+			break
+		}
+
+		select {
+		case work <- w:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	total.Retries = budget.used
+	total.RetryBudgetRemaining = budget.remaining()
+
+	if firstErr == nil && cutShort(ctx) {
+		total.Partial = true
+	} else if firstErr == nil && ctx.Err() != nil {
+		firstErr = ctx.Err()
+	}
+
+	return total, firstErr
+}
+
+// backfillChunk fetches a single chunk, retrying failures within the budget,
+// and writes it to the client's destination.
+func (c *Client) backfillChunk(ctx context.Context, zoneID string, w TimeRange, o *BackfillOptions, budget *retryBudget, seq *sequencer, destMu *sync.Mutex) (*Meta, error) {
+	u, err := c.timestampURL(ctx, zoneID, w.Start.Unix(), w.End.Unix(), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	backoff := o.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		var buf bytes.Buffer
+		meta, err := c.request(ctx, u, c.newSharedLogWriter(&buf, seq))
+		if meta != nil && meta.StatusCode == http.StatusNoContent {
+			return meta, nil
+		}
+
+		if err == nil && meta.Partial {
+			return meta, nil
+		}
+
+		if err == nil {
+			destMu.Lock()
+			_, err = buf.WriteTo(c.dest)
+			if s, ok := c.dest.(syncer); ok && err == nil && c.fsync {
+				if err = flushWriter(c.dest); err == nil {
+					err = s.Sync()
+				}
+			}
+			destMu.Unlock()
+			return meta, errors.Wrap(err, "failed to write chunk")
+		}
+
+		if attempt >= o.MaxRetries || !retryable(ctx, meta, err) {
+			return meta, err
+		}
+
+		if !budget.take() {
+			return meta, errors.Wrap(ErrRetryBudgetExhausted, err.Error())
+		}
+		c.metrics.IncRetries()
+
+		if err := sleepContext(ctx, backoff); err != nil {
+			return meta, err
+		}
+		backoff *= 2
+	}
+}
+
+// splitWindows splits the range from start to end into windows of at most
+// size.
+func splitWindows(start time.Time, end time.Time, size time.Duration) []TimeRange {
+	var windows []TimeRange
+	for s := start; s.Before(end); s = s.Add(size) {
+		e := s.Add(size)
+		if e.After(end) {
+			e = end
+		}
+		windows = append(windows, TimeRange{Start: s, End: e})
+	}
+
+	return windows
+}
@@ -0,0 +1,131 @@
+package logshare
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultCircuitCooldown is how long the circuit breaker stays open when no
+// Options.CircuitCooldown is set.
+const DefaultCircuitCooldown = 30 * time.Second
+
+// ErrCircuitOpen is returned, without making a request, while the circuit
+// breaker is open after repeated failures (see Options.CircuitThreshold).
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// The states of the circuit breaker, as reported in Meta.CircuitState.
+const (
+	// CircuitClosed allows every request.
+	CircuitClosed = "closed"
+	// CircuitOpen fails every request with ErrCircuitOpen until the
+	// cooldown has passed.
+	CircuitOpen = "open"
+	// CircuitHalfOpen allows a single probe request once the cooldown has
+	// passed: if it succeeds the circuit closes, otherwise it opens again.
+	CircuitHalfOpen = "half-open"
+)
+
+// circuitBreaker counts consecutive failed requests, and stops requests for a
+// cooldown period once there have been threshold of them. A nil
+// circuitBreaker allows every request.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+
+	if cooldown <= 0 {
+		cooldown = DefaultCircuitCooldown
+	}
+
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow returns ErrCircuitOpen if a request may not be made.
+func (b *circuitBreaker) allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return nil
+	}
+
+	if wait := b.cooldown - time.Since(b.openedAt); wait > 0 {
+		return errors.Wrapf(ErrCircuitOpen, "%d consecutive failures, retry in %s", b.failures, wait.Round(time.Second))
+	}
+
+	if b.probing {
+		return errors.Wrap(ErrCircuitOpen, "waiting for a probe request")
+	}
+	b.probing = true
+
+	return nil
+}
+
+// done records the outcome of an allowed request.
+func (b *circuitBreaker) done(failed bool) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if !failed {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = time.Now()
+	}
+}
+
+// abandon releases an allowed request that ended without an outcome, such as
+// one whose context was cancelled.
+func (b *circuitBreaker) abandon() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+// state returns the current state of the breaker, or an empty string if there
+// is no breaker.
+func (b *circuitBreaker) state() string {
+	if b == nil {
+		return ""
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case b.failures < b.threshold:
+		return CircuitClosed
+	case time.Since(b.openedAt) < b.cooldown:
+		return CircuitOpen
+	}
+
+	return CircuitHalfOpen
+}
@@ -0,0 +1,190 @@
+package logshare
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"time"
+)
+
+// Defaults for output buffering, used for any of WriteBufferLines,
+// WriteBufferBytes and WriteBufferInterval not set when another is.
+const (
+	DefaultWriteBufferLines    = 1000
+	DefaultWriteBufferBytes    = 64 << 10
+	DefaultWriteBufferInterval = time.Second
+)
+
+// buffered reports whether output buffering is configured.
+func (c *Client) buffered() bool {
+	return c.bufferLines > 0 || c.bufferBytes > 0 || c.bufferInterval > 0
+}
+
+// newBufferedFormatWriter returns a logWriter in the client's output format
+// that writes to w through a buffer, if output buffering is configured, and
+// directly otherwise.
+func (c *Client) newBufferedFormatWriter(w io.Writer) logWriter {
+	if !c.buffered() {
+		return c.newFormatWriter(w)
+	}
+
+	lines, size, interval := c.bufferLines, c.bufferBytes, c.bufferInterval
+	if lines <= 0 {
+		lines = DefaultWriteBufferLines
+	}
+	if size <= 0 {
+		size = DefaultWriteBufferBytes
+	}
+	if interval <= 0 {
+		interval = DefaultWriteBufferInterval
+	}
+
+	buf := &outputBuffer{w: bufio.NewWriterSize(w, size), size: size, interval: interval}
+
+	return &bufferWriter{forwarder: forwarder{next: c.newFormatWriter(buf)}, buf: buf, lines: lines}
+}
+
+// outputBuffer buffers writes to the destination, flushing once size bytes
+// are buffered or interval after the oldest buffered write, whichever comes
+// first. The timed flush runs on its own goroutine, so that a quiet stream
+// is not held back, hence the lock.
+type outputBuffer struct {
+	mu       sync.Mutex
+	w        *bufio.Writer
+	size     int
+	interval time.Duration
+	timer    *time.Timer
+	// An error from a timed flush, returned by the next write.
+	err error
+}
+
+func (b *outputBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	n, err := b.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	if b.w.Buffered() > 0 && b.timer == nil {
+		b.timer = time.AfterFunc(b.interval, b.timedFlush)
+	}
+
+	return n, nil
+}
+
+// full reports whether the size threshold has been reached. Checking between
+// logs, rather than leaving it to the bufio.Writer, keeps flushes on line
+// boundaries.
+func (b *outputBuffer) full() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.w.Buffered() >= b.size
+}
+
+func (b *outputBuffer) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.flush()
+}
+
+func (b *outputBuffer) timedFlush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.timer = nil
+	if err := b.flush(); err != nil && b.err == nil {
+		b.err = err
+	}
+}
+
+func (b *outputBuffer) flush() error {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+
+	if b.err != nil {
+		return b.err
+	}
+
+	return b.w.Flush()
+}
+
+// bufferFlusher is implemented by logWriters that buffer output, and by those
+// that wrap one, so that a writer further up the chain, such as a syncWriter,
+// can get the buffered logs to the destination before acting on it.
+type bufferFlusher interface {
+	flushBuffer() error
+}
+
+// flushBuffer writes out any output buffered by lw.
+func flushBuffer(lw logWriter) error {
+	if f, ok := lw.(bufferFlusher); ok {
+		return f.flushBuffer()
+	}
+
+	return nil
+}
+
+// bufferWriter is a logWriter that flushes an outputBuffer every so many
+// logs, when it fills, and once the logs have been written.
+type bufferWriter struct {
+	forwarder
+	buf   *outputBuffer
+	lines int
+	n     int
+}
+
+func (b *bufferWriter) writeLog(line []byte) error {
+	if err := b.next.writeLog(line); err != nil {
+		return err
+	}
+
+	return b.wrote()
+}
+
+func (b *bufferWriter) writeRecord(rec LogRecord) error {
+	if err := writeRecordTo(b.next, rec); err != nil {
+		return err
+	}
+
+	return b.wrote()
+}
+
+func (b *bufferWriter) wrote() error {
+	b.n++
+	if b.n%b.lines == 0 || b.buf.full() {
+		return b.buf.Flush()
+	}
+
+	return nil
+}
+
+func (b *bufferWriter) close() error {
+	err := b.next.close()
+	if ferr := b.buf.Flush(); err == nil {
+		err = ferr
+	}
+
+	return err
+}
+
+func (b *bufferWriter) flushBuffer() error {
+	return b.buf.Flush()
+}
+
+func (b *bufferWriter) writeSummary(line []byte) error {
+	if err := writeSummaryTo(b.next, line); err != nil {
+		return err
+	}
+
+	return b.buf.Flush()
+}
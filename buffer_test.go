@@ -0,0 +1,121 @@
+package logshare
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// syncRecorder is a destination that records what had been written each time
+// it was synced.
+type syncRecorder struct {
+	bytes.Buffer
+	synced []string
+}
+
+func (s *syncRecorder) Sync() error {
+	s.synced = append(s.synced, s.String())
+	return nil
+}
+
+func TestWriteBufferFlushedBeforeSync(t *testing.T) {
+	dest := &syncRecorder{}
+	c, err := New("token", "", "", &Options{
+		Dest:             dest,
+		WriteBufferLines: 100,
+		FsyncOnComplete:  true,
+		FsyncEvery:       2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lw := c.newLogWriter(dest)
+	logs := strings.Split(strings.TrimSuffix(testLogs(4), "\n"), "\n")
+	for _, line := range logs {
+		if err := lw.writeLog([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := lw.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{testLogs(2), testLogs(4), testLogs(4)}
+	if fmt.Sprint(dest.synced) != fmt.Sprint(want) {
+		t.Errorf("synced after %q, want %q", dest.synced, want)
+	}
+}
+
+func TestWriteBufferFlushedBeforeHTTPFlush(t *testing.T) {
+	rec := httptest.NewRecorder()
+	c, err := New("token", "", "", &Options{WriteBufferLines: 100, FlushEvery: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lw := c.newLogWriter(rec)
+	logs := strings.Split(strings.TrimSuffix(testLogs(2), "\n"), "\n")
+	for _, line := range logs {
+		if err := lw.writeLog([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !rec.Flushed {
+		t.Fatal("response not flushed after FlushEvery logs")
+	}
+	if rec.Body.String() != testLogs(2) {
+		t.Errorf("flushed %q, want %q", rec.Body.String(), testLogs(2))
+	}
+
+	if err := lw.close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// BenchmarkWriteBuffering measures writing logs to a file with and without
+// output buffering, where each unbuffered log costs a write system call.
+func BenchmarkWriteBuffering(b *testing.B) {
+	line := []byte(strings.TrimSuffix(testLogs(1), "\n"))
+
+	for _, bm := range []struct {
+		name string
+		opts Options
+	}{
+		{name: "unbuffered"},
+		{name: "buffered", opts: Options{WriteBufferLines: DefaultWriteBufferLines}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			f, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer f.Close()
+
+			opts := bm.opts
+			opts.Dest = f
+			c, err := New("token", "", "", &opts)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			lw := c.newLogWriter(f)
+			b.SetBytes(int64(len(line)) + 1)
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if err := lw.writeLog(line); err != nil {
+					b.Fatal(err)
+				}
+			}
+			if err := lw.close(); err != nil {
+				b.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package logshare
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// DefaultResponseCap is the number of logs the API returns at most when no
+// count is given, for endpoints that cap their responses.
+const DefaultResponseCap = 1000
+
+// CapPolicy controls what happens when a request made without a count returns
+// exactly Options.ResponseCap logs, and so was likely cut short by the API.
+type CapPolicy int
+
+const (
+	// CapFlag sets Meta.CapReached, leaving the caller to decide whether to
+	// fetch the rest. This is the default.
+	CapFlag CapPolicy = iota
+	// CapPaginate fetches the rest of the window from the RayID of the last
+	// log received, as when paginating beyond MaxCount, until a response
+	// returns fewer logs than the cap. Likewise, a count over the cap is
+	// fetched in pages of the cap, until count logs have been written or the
+	// logs run out; the log at each page boundary is written once. Either
+	// way, the pages are written as one pull, summarized in one Meta. It
+	// applies to pulls by timestamp (such as GetFromTimestamp, GetLast and
+	// Incremental), and requires RayID in Fields (if Fields are set), and an
+	// end timestamp without a count; other pulls fall back to CapFlag.
+	CapPaginate
+)
+
+// capReached reports whether a request returned as many logs as the API's cap
+// for requests without a count.
+func (c *Client) capReached(count int, meta *Meta) bool {
+	return c.responseCap > 0 && count <= 0 && meta.Count >= c.responseCap
+}
+
+// queryCount returns the count parameter of u, or zero if it has none.
+func queryCount(u *url.URL) int {
+	n, _ := strconv.Atoi(u.Query().Get("count"))
+	return n
+}
@@ -0,0 +1,88 @@
+package logshare
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CountSupport is whether an endpoint takes a count parameter.
+type CountSupport int
+
+const (
+	// CountOptional endpoints take a count, or return every log in the
+	// window without one.
+	CountOptional CountSupport = iota
+	// CountRequired endpoints must be sent a count. A request without one is
+	// sent Options.ResponseCap as its count, or fails if there is none.
+	CountRequired
+	// CountForbidden endpoints reject a count. A request with one fails
+	// before it is sent.
+	CountForbidden
+)
+
+// EndpointCapabilities describes the parameters an endpoint accepts, which
+// differ between Log Share products.
+type EndpointCapabilities struct {
+	Count CountSupport
+	// Whether the endpoint accepts its parameters in a POST body (see
+	// Options.RequestMethod).
+	AcceptsPost bool
+}
+
+// DefaultEndpointCapabilities describes the endpoints of the current API, by
+// the last element of their path: "received", "requests", and "rayids" for
+// single ray IDs. Options.EndpointCapabilities overrides them.
+var DefaultEndpointCapabilities = map[string]EndpointCapabilities{
+	byReceived: {Count: CountOptional, AcceptsPost: true},
+	byRequest:  {Count: CountOptional, AcceptsPost: true},
+	"rayids":   {Count: CountForbidden},
+}
+
+// capabilities returns the capabilities of the named endpoint.
+func (c *Client) capabilities(endpoint string) EndpointCapabilities {
+	if caps, ok := c.endpointCaps[endpoint]; ok {
+		return caps
+	}
+
+	return DefaultEndpointCapabilities[endpoint]
+}
+
+// endpointOf returns the name of the logs endpoint u is for, such as
+// "received", or "received/fields" for the field names.
+func endpointOf(u *url.URL) string {
+	i := strings.LastIndex(u.Path, "/logs/")
+	if i < 0 {
+		return ""
+	}
+
+	name := u.Path[i+len("/logs/"):]
+	if strings.HasPrefix(name, "rayids/") {
+		return "rayids"
+	}
+
+	return name
+}
+
+// applyCount checks the count in params against what the endpoint accepts,
+// adding one where it is required.
+func (c *Client) applyCount(endpoint string, params url.Values) error {
+	switch c.capabilities(endpoint).Count {
+	case CountRequired:
+		if params.Get("count") != "" {
+			return nil
+		}
+		if c.responseCap <= 0 {
+			return errors.Errorf("the %s endpoint requires a count", endpoint)
+		}
+		params.Set("count", strconv.Itoa(c.responseCap))
+	case CountForbidden:
+		if params.Get("count") != "" {
+			return errors.Errorf("the %s endpoint does not accept a count", endpoint)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package logshare
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The methods below write to the given io.WriteCloser instead of
+// Options.Dest, and take ownership of it: once the pull completes, whether or
+// not it succeeded, the writer is flushed (if it has a Flush method) and
+// closed, so that compressed or rotated output is always finished. The
+// caller must not close it again. Where the caller manages the lifecycle of
+// its destination, use the methods that write to Options.Dest instead, which
+// never close it.
+
+// GetFromTimestampAndClose is like GetFromTimestamp, but writes to wc and
+// closes it.
+func (c *Client) GetFromTimestampAndClose(zoneID string, start int64, end int64, count int, wc io.WriteCloser) (*Meta, error) {
+	meta, err := c.getFromTimestamp(context.Background(), zoneID, start, end, count, c.newLogWriter(wc))
+	return meta, closeDest(wc, err)
+}
+
+// GetFromRayIDAndClose is like GetFromRayID, but writes to wc and closes it.
+func (c *Client) GetFromRayIDAndClose(zoneID string, rayID string, end int64, count int, wc io.WriteCloser) (*Meta, error) {
+	meta, err := c.getFromRayID(context.Background(), zoneID, rayID, end, count, c.newLogWriter(wc))
+	return meta, closeDest(wc, err)
+}
+
+// GetLastAndClose is like GetLast, but writes to wc and closes it.
+func (c *Client) GetLastAndClose(zoneID string, d time.Duration, count int, wc io.WriteCloser) (*Meta, error) {
+	start, end := c.WindowLast(d)
+	meta, err := c.getFromTimestamp(context.Background(), zoneID, start.Unix(), end.Unix(), count, c.newLogWriter(wc))
+	return meta, closeDest(wc, err)
+}
+
+// GetByRayIDsAndClose is like GetByRayIDs, but closes wc once done.
+func (c *Client) GetByRayIDsAndClose(ctx context.Context, zoneID string, rayIDs []string, wc io.WriteCloser) (*Meta, error) {
+	meta, err := c.GetByRayIDs(ctx, zoneID, rayIDs, wc)
+	return meta, closeDest(wc, err)
+}
+
+// closeDest flushes and closes wc, returning err along with any error from
+// doing so.
+func closeDest(wc io.WriteCloser, err error) error {
+	ferr := flushWriter(wc)
+	if cerr := wc.Close(); ferr == nil {
+		ferr = cerr
+	}
+
+	switch {
+	case ferr == nil:
+		return err
+	case err == nil:
+		return errors.Wrap(ferr, "failed to close destination")
+	}
+
+	return errors.Wrapf(err, "also failed to close destination: %v", ferr)
+}
@@ -10,8 +10,8 @@ import (
 
 	gcs "cloud.google.com/go/storage"
 	cloudflare "github.com/cloudflare/cloudflare-go"
-	"github.com/ramann/logshare"
 	"github.com/pkg/errors"
+	"github.com/ramann/logshare"
 	"github.com/urfave/cli"
 	"golang.org/x/net/context"
 )
@@ -116,6 +116,17 @@ func run(conf *config) func(c *cli.Context) error {
 			if err != nil {
 				return errors.Wrap(err, "failed to fetch field names")
 			}
+		} else if conf.last > 0 {
+			meta, err = client.GetLast(conf.zoneID, conf.last, conf.count)
+			if err != nil {
+				return errors.Wrap(err, "failed to fetch the last logs")
+			}
+		} else if conf.rayID != "" {
+			meta, err = client.GetFromRayID(
+				conf.zoneID, conf.rayID, conf.endTime, conf.count)
+			if err != nil {
+				return errors.Wrap(err, "failed to fetch via rayID")
+			}
 		} else {
 			meta, err = client.GetFromTimestamp(
 				conf.zoneID, conf.startTime, conf.endTime, conf.count)
@@ -138,8 +149,10 @@ func parseFlags(conf *config, c *cli.Context) error {
 	conf.apiEmail = c.String("api-email")
 	conf.zoneID = c.String("zone-id")
 	conf.zoneName = c.String("zone-name")
+	conf.rayID = c.String("ray-id")
 	conf.startTime = c.Int64("start-time")
 	conf.endTime = c.Int64("end-time")
+	conf.last = c.Duration("last")
 	conf.count = c.Int("count")
 	conf.timestampFormat = c.String("timestamp-format")
 	conf.sample = c.Float64("sample")
@@ -158,8 +171,10 @@ type config struct {
 	apiEmail            string
 	zoneID              string
 	zoneName            string
+	rayID               string
 	startTime           int64
 	endTime             int64
+	last                time.Duration
 	count               int
 	timestampFormat     string
 	sample              float64
@@ -193,7 +208,7 @@ func (conf *config) Validate() error {
 
 var flags = []cli.Flag{
 	cli.StringFlag{
-		Name: "api-token",
+		Name:  "api-token",
 		Usage: "Your Cloudflare API token",
 	},
 	cli.StringFlag{
@@ -226,6 +241,10 @@ var flags = []cli.Flag{
 		Value: time.Now().Add(-time.Minute * 20).Unix(),
 		Usage: "The timestamp (in Unix seconds) to request logs to. Defaults to 20 minutes behind the current time",
 	},
+	cli.DurationFlag{
+		Name:  "last",
+		Usage: "Fetch logs from this long before the most recent available logs (e.g. '15m'), instead of --start-time and --end-time",
+	},
 	cli.IntFlag{
 		Name:  "count",
 		Value: 1,
@@ -0,0 +1,174 @@
+package logshare
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// RecordCodec serializes decoded logs. When set in Options.RecordCodec, it is
+// used both to write logs to the destination (in place of Options.Format) and
+// to encode the Raw bytes delivered by StreamRecords.
+type RecordCodec interface {
+	// Encode writes a single log to w, including any record terminator.
+	Encode(w io.Writer, rec LogRecord) error
+	// Decode parses a single log previously written by Encode.
+	Decode(data []byte) (LogRecord, error)
+}
+
+// JSONCodec encodes logs as newline delimited JSON with sorted keys.
+type JSONCodec struct{}
+
+// Encode implements RecordCodec.
+func (JSONCodec) Encode(w io.Writer, rec LogRecord) error {
+	return json.NewEncoder(w).Encode(rec)
+}
+
+// Decode implements RecordCodec.
+func (JSONCodec) Decode(data []byte) (LogRecord, error) {
+	return decodeLog(data)
+}
+
+// CSVCodec encodes logs as CSV rows with the given columns. No header row is
+// written. Decoded values are always strings.
+type CSVCodec struct {
+	Columns []string
+}
+
+// Encode implements RecordCodec.
+func (c CSVCodec) Encode(w io.Writer, rec LogRecord) error {
+	row := make([]string, len(c.Columns))
+	for i, col := range c.Columns {
+		row[i] = csvValue(rec[col])
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write(row)
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// Decode implements RecordCodec.
+func (c CSVCodec) Decode(data []byte) (LogRecord, error) {
+	row, err := csv.NewReader(bytes.NewReader(data)).Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode CSV row")
+	}
+
+	if len(row) != len(c.Columns) {
+		return nil, errors.Errorf("CSV row has %d columns, expected %d", len(row), len(c.Columns))
+	}
+
+	rec := make(LogRecord, len(row))
+	for i, col := range c.Columns {
+		rec[col] = row[i]
+	}
+
+	return rec, nil
+}
+
+// codecWriter is a logWriter that re-encodes each log with a RecordCodec.
+type codecWriter struct {
+	w     io.Writer
+	codec RecordCodec
+}
+
+func (c *codecWriter) writeLog(line []byte) error {
+	rec, err := decodeLog(line)
+	if err != nil {
+		return err
+	}
+
+	return c.writeRecord(rec)
+}
+
+func (c *codecWriter) writeRecord(rec LogRecord) error {
+	return c.codec.Encode(c.w, rec)
+}
+
+func (c *codecWriter) close() error { return nil }
+
+// StreamedRecord is a log delivered by StreamRecords.
+type StreamedRecord struct {
+	Record LogRecord
+	// The log encoded with Options.RecordCodec. When no codec is set, this is
+	// the raw JSON line from the API, or the log re-encoded as JSON if it was
+	// changed by a record-level option such as AddIngestField.
+	Raw []byte
+}
+
+// StreamRecords fetches logs between the start and end timestamps (up to
+// 'count' logs) and delivers them decoded on the returned channel. The error
+// channel receives at most one error, once the record channel is closed.
+//
+// Cancelling the context stops the stream and closes both channels.
+func (c *Client) StreamRecords(ctx context.Context, zoneID string, start int64, end int64, count int) (<-chan StreamedRecord, <-chan error) {
+	records := make(chan StreamedRecord)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+		defer close(records)
+
+		cw := &channelWriter{ctx: ctx, ch: records, codec: c.codec}
+		if _, err := c.getFromTimestamp(ctx, zoneID, start, end, count, c.withRecordStage(cw)); err != nil {
+			errc <- err
+		}
+	}()
+
+	return records, errc
+}
+
+// channelWriter is a logWriter that sends decoded logs on a channel.
+type channelWriter struct {
+	ctx   context.Context
+	ch    chan<- StreamedRecord
+	codec RecordCodec
+	buf   bytes.Buffer
+}
+
+func (c *channelWriter) writeLog(line []byte) error {
+	rec, err := decodeLog(line)
+	if err != nil {
+		return err
+	}
+
+	if c.codec != nil {
+		return c.writeRecord(rec)
+	}
+
+	return c.send(rec, append([]byte(nil), line...))
+}
+
+func (c *channelWriter) writeRecord(rec LogRecord) error {
+	if c.codec == nil {
+		raw, err := json.Marshal(rec)
+		if err != nil {
+			return errors.Wrap(err, "failed to encode log")
+		}
+		return c.send(rec, raw)
+	}
+
+	c.buf.Reset()
+	if err := c.codec.Encode(&c.buf, rec); err != nil {
+		return errors.Wrap(err, "failed to encode log")
+	}
+
+	return c.send(rec, append([]byte(nil), c.buf.Bytes()...))
+}
+
+func (c *channelWriter) send(rec LogRecord, raw []byte) error {
+	select {
+	case c.ch <- StreamedRecord{Record: rec, Raw: raw}:
+		return nil
+	case <-c.ctx.Done():
+		return c.ctx.Err()
+	}
+}
+
+func (c *channelWriter) close() error { return nil }
@@ -0,0 +1,54 @@
+package logshare
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// PullToCommand fetches logs between the start and end timestamps (up to
+// 'count' logs) and writes them to the standard input of cmd, which must not
+// have been started and must not have Stdin set. The command is started
+// before the pull and waited for after it, and its stdin is closed once every
+// log has been written.
+//
+// If the command exits before reading every log (e.g. `head`), the resulting
+// broken pipe is not treated as an error: the command's own exit status is
+// returned instead.
+func (c *Client) PullToCommand(ctx context.Context, zoneID string, start int64, end int64, count int, cmd *exec.Cmd) (*Meta, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open command stdin")
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, errors.Wrap(err, "failed to start command")
+	}
+
+	meta, pullErr := c.getFromTimestamp(ctx, zoneID, start, end, count, c.newLogWriter(stdin))
+	stdin.Close()
+
+	waitErr := cmd.Wait()
+	switch {
+	case pullErr != nil && !isBrokenPipe(pullErr):
+		return meta, pullErr
+	case waitErr != nil:
+		return meta, errors.Wrap(waitErr, "command failed")
+	}
+
+	return meta, nil
+}
+
+// isBrokenPipe reports whether err was caused by writing to a pipe whose
+// reader has gone away.
+func isBrokenPipe(err error) bool {
+	err = errors.Cause(err)
+	if pe, ok := err.(*os.PathError); ok {
+		err = pe.Err
+	}
+
+	return err == syscall.EPIPE || err == os.ErrClosed
+}
@@ -0,0 +1,76 @@
+package logshare
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http/httptrace"
+	"net/url"
+	"time"
+)
+
+// DefaultConnRetryDelay is the default Options.ConnRetryDelay.
+const DefaultConnRetryDelay = time.Second
+
+// maxConnRetryDelay caps the doubling wait between connection retries.
+const maxConnRetryDelay = time.Minute
+
+// connError is returned by requestOnce when a request failed before a
+// connection was established, so nothing was sent and it is safe to retry.
+type connError struct {
+	err error
+}
+
+func (e *connError) Error() string {
+	return e.err.Error()
+}
+
+func (e *connError) Cause() error {
+	return e.err
+}
+
+// traceConnected returns a copy of ctx that sets connected once the request
+// has a connection to send on.
+func traceConnected(ctx context.Context, connected *bool) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(httptrace.GotConnInfo) { *connected = true },
+	})
+}
+
+// connRetryable reports whether err, from a request that never got a
+// connection, is worth retrying: DNS, dial and TLS handshake failures are,
+// but a certificate the client rejects will be rejected again.
+func connRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	// Newer Go versions wrap certificate errors in a type of their own.
+	for err != nil {
+		switch e := err.(type) {
+		case x509.UnknownAuthorityError, x509.HostnameError, x509.CertificateInvalidError:
+			return false
+		case *url.Error:
+			err = e.Err
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		default:
+			return true
+		}
+	}
+
+	return true
+}
+
+// connRetryDelay returns the wait before the given connection retry,
+// doubling from ConnRetryDelay up to maxConnRetryDelay.
+func (c *Client) connRetryDelay(retries int) time.Duration {
+	d := c.connRetryBase
+	for i := 0; i < retries && d < maxConnRetryDelay; i++ {
+		d *= 2
+	}
+	if d > maxConnRetryDelay {
+		d = maxConnRetryDelay
+	}
+
+	return d
+}
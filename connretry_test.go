@@ -0,0 +1,144 @@
+package logshare
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConnRetryDelay(t *testing.T) {
+	c := &Client{connRetryBase: 10 * time.Second}
+	want := []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute}
+	for retries, d := range want {
+		if got := c.connRetryDelay(retries); got != d {
+			t.Errorf("connRetryDelay(%d) = %v, want %v", retries, got, d)
+		}
+	}
+}
+
+func TestConnRetryable(t *testing.T) {
+	dial := &url.Error{Op: "Get", URL: "https://example.com", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}
+	cert := &url.Error{Op: "Get", URL: "https://example.com", Err: x509.UnknownAuthorityError{}}
+
+	if !connRetryable(context.Background(), dial) {
+		t.Error("dial error not retryable")
+	}
+	if connRetryable(context.Background(), cert) {
+		t.Error("certificate error retryable")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if connRetryable(ctx, dial) {
+		t.Error("error after cancellation retryable")
+	}
+}
+
+// retryMetrics is a MetricsRecorder that counts retries.
+type retryMetrics struct {
+	NopMetrics
+
+	mu      sync.Mutex
+	retries int
+}
+
+func (r *retryMetrics) IncRetries() {
+	r.mu.Lock()
+	r.retries++
+	r.mu.Unlock()
+}
+
+// flakyDialer fails the first n dials.
+type flakyDialer struct {
+	mu    sync.Mutex
+	n     int
+	dials []time.Time
+}
+
+func (f *flakyDialer) DialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	f.mu.Lock()
+	f.dials = append(f.dials, time.Now())
+	fail := len(f.dials) <= f.n
+	f.mu.Unlock()
+
+	if fail {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: errors.New("connection refused")}
+	}
+
+	var d net.Dialer
+	return d.DialContext(ctx, network, addr)
+}
+
+func TestConnRetries(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"RayID":"a"}`)
+	}))
+	defer srv.Close()
+
+	const delay = 20 * time.Millisecond
+	start := time.Now().Add(-time.Hour).Unix()
+
+	tests := []struct {
+		name    string
+		fails   int
+		retries int
+		err     bool
+	}{
+		{name: "no failures", fails: 0, retries: 2},
+		{name: "recovers", fails: 2, retries: 2},
+		{name: "gives up", fails: 3, retries: 2, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &flakyDialer{n: tt.fails}
+			metrics := &retryMetrics{}
+			c, err := New("token", "", "", &Options{
+				Transport:      &http.Transport{DialContext: d.DialContext},
+				ConnRetries:    tt.retries,
+				ConnRetryDelay: delay,
+				Metrics:        metrics,
+				Dest:           ioutil.Discard,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.endpoint = srv.URL
+
+			meta, err := c.GetFromTimestamp(testZoneID, start, start+60, 0)
+			if tt.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if meta.ConnRetries != tt.fails {
+					t.Errorf("ConnRetries = %d, want %d", meta.ConnRetries, tt.fails)
+				}
+			}
+
+			if want := len(d.dials) - 1; metrics.retries != want {
+				t.Errorf("counted %d retries, want %d", metrics.retries, want)
+			}
+
+			// Each wait doubles the last.
+			for i := 1; i < len(d.dials); i++ {
+				want := delay << uint(i-1)
+				if got := d.dials[i].Sub(d.dials[i-1]); got < want {
+					t.Errorf("retry %d after %v, want at least %v", i, got, want)
+				}
+			}
+		})
+	}
+}
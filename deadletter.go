@@ -0,0 +1,69 @@
+package logshare
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// deadLetter writes logs that could not be decoded or transformed to
+// Options.DeadLetterWriter. It is shared by the pulls of a client, which may
+// run concurrently.
+type deadLetter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func newDeadLetter(w io.Writer) *deadLetter {
+	if w == nil {
+		return nil
+	}
+
+	return &deadLetter{w: w}
+}
+
+// write writes a log to the dead-letter writer as a line of JSON holding the
+// error and the log, as a string.
+func (d *deadLetter) write(log []byte, cause error) error {
+	line, err := json.Marshal(struct {
+		Error string `json:"error"`
+		Log   string `json:"log"`
+	}{cause.Error(), string(log)})
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, err := d.w.Write(append(line, '\n')); err != nil {
+		return errors.Wrap(err, "failed to write to DeadLetterWriter")
+	}
+
+	return nil
+}
+
+// reject routes a log that failed in a recordStage to the dead-letter writer,
+// if there is one, and otherwise returns the failure. line is the raw log, or
+// nil to encode rec in its place.
+func (r *recordStage) reject(line []byte, rec LogRecord, cause error) error {
+	if r.deadLetter == nil {
+		return cause
+	}
+
+	if line == nil {
+		var err error
+		if line, err = json.Marshal(rec); err != nil {
+			return cause
+		}
+	}
+
+	if err := r.deadLetter.write(line, cause); err != nil {
+		return err
+	}
+	r.deadLettered++
+
+	return nil
+}
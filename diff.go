@@ -0,0 +1,107 @@
+package logshare
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// PullDiff is the result of DiffPulls.
+type PullDiff struct {
+	// The RayIDs in the first pull but not the second, and in the second but
+	// not the first.
+	Missing []string
+	Extra   []string
+	// The number of RayIDs in both pulls.
+	Common int
+}
+
+// DiffPulls compares two pulls of the same window, such as one from Logpull
+// and one from Logpush, and reports the logs (by RayID) present in one but not
+// the other. Both inputs must be newline-delimited JSON, sorted by RayID (for
+// example with `jq -c -s 'sort_by(.RayID)[]'`), so that they can be compared
+// in a single pass without holding either in memory; an input that is out of
+// order is an error. Repeated RayIDs are compared once.
+func DiffPulls(a io.Reader, b io.Reader) (*PullDiff, error) {
+	ra, rb := newRayReader(a, "first"), newRayReader(b, "second")
+	diff := &PullDiff{}
+
+	x, err := ra.next()
+	if err != nil {
+		return nil, err
+	}
+	y, err := rb.next()
+	if err != nil {
+		return nil, err
+	}
+
+	for x != "" || y != "" {
+		switch {
+		case y == "" || (x != "" && x < y):
+			diff.Missing = append(diff.Missing, x)
+			x, err = ra.next()
+		case x == "" || y < x:
+			diff.Extra = append(diff.Extra, y)
+			y, err = rb.next()
+		default:
+			diff.Common++
+			if x, err = ra.next(); err == nil {
+				y, err = rb.next()
+			}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return diff, nil
+}
+
+// rayReader reads the RayIDs of a stream of logs sorted by RayID.
+type rayReader struct {
+	scanner *bufio.Scanner
+	name    string
+	line    int
+	last    string
+}
+
+func newRayReader(r io.Reader, name string) *rayReader {
+	return &rayReader{scanner: bufio.NewScanner(r), name: name}
+}
+
+// next returns the next distinct RayID, or an empty string at the end of the
+// stream.
+func (r *rayReader) next() (string, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := r.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var rec struct{ RayID string }
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return "", errors.Wrapf(err, "%s pull, line %d: failed to decode log", r.name, r.line)
+		}
+
+		if rec.RayID == "" {
+			return "", errors.Errorf("%s pull, line %d: log has no RayID", r.name, r.line)
+		}
+
+		if rec.RayID < r.last {
+			return "", errors.Errorf("%s pull, line %d: RayID %s is out of order", r.name, r.line, rec.RayID)
+		}
+
+		if rec.RayID == r.last {
+			continue
+		}
+		r.last = rec.RayID
+
+		return rec.RayID, nil
+	}
+
+	return "", errors.Wrapf(r.scanner.Err(), "failed to read %s pull", r.name)
+}
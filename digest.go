@@ -0,0 +1,110 @@
+package logshare
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// PullDigest summarizes the logs of a pull, for indexing the raw output
+// alongside it.
+type PullDigest struct {
+	// The number of logs written.
+	Count int
+	// The number of logs with each EdgeResponseStatus.
+	StatusCodes map[int]int
+	// The earliest and latest EdgeStartTimestamp, or zero if no log had one.
+	MinTimestamp time.Time
+	MaxTimestamp time.Time
+}
+
+// GetDigestFromTimestamp fetches the logs between the start and end
+// timestamps (up to 'count' logs) into the client's destination writer, like
+// GetFromTimestamp, while summarizing them in Meta.Digest. Each log is
+// summarized as it is written, after record-level options (such as
+// HostFilter or SampleSeed) and in a single pass, by reading only the
+// EdgeResponseStatus and EdgeStartTimestamp fields (by their names after
+// RenameFields), which should be in Fields.
+func (c *Client) GetDigestFromTimestamp(ctx context.Context, zoneID string, start int64, end int64, count int) (*Meta, error) {
+	dw := &digestWriter{
+		forwarder: forwarder{next: c.withSync(c.dest, c.withFlusher(c.dest, c.newBufferedFormatWriter(c.dest)))},
+		digest:    &PullDigest{StatusCodes: make(map[int]int)},
+		status:    c.renamed(StatusField),
+		timestamp: c.renamed(TimestampField),
+	}
+	lw := c.withReverse(c.withReorder(c.withRecordStage(dw)))
+	meta, err := c.getFromTimestamp(ctx, zoneID, start, end, count, lw)
+	if meta != nil {
+		meta.Digest = dw.digest
+		if meta.StatusCode == http.StatusNoContent {
+			err = nil
+		}
+	}
+
+	return meta, err
+}
+
+// digestWriter is a logWriter that summarizes logs as they are passed to the
+// next logWriter, reading the status and timestamp fields of the given names.
+type digestWriter struct {
+	forwarder
+	digest    *PullDigest
+	status    string
+	timestamp string
+}
+
+func (d *digestWriter) writeLog(line []byte) error {
+	status, _ := strconv.Atoi(string(lineValue(line, d.status)))
+	t, ok := rawTime(lineValue(line, d.timestamp))
+	d.observe(status, t, ok)
+
+	return d.next.writeLog(line)
+}
+
+func (d *digestWriter) writeRecord(rec LogRecord) error {
+	status, _ := strconv.Atoi(csvValue(rec[d.status]))
+	t, ok := recordTime(rec, d.timestamp)
+	d.observe(status, t, ok)
+
+	return writeRecordTo(d.next, rec)
+}
+
+func (d *digestWriter) observe(status int, t time.Time, timed bool) {
+	if status != 0 {
+		d.digest.StatusCodes[status]++
+	}
+	if timed {
+		if d.digest.MinTimestamp.IsZero() || t.Before(d.digest.MinTimestamp) {
+			d.digest.MinTimestamp = t
+		}
+		if t.After(d.digest.MaxTimestamp) {
+			d.digest.MaxTimestamp = t
+		}
+	}
+	d.digest.Count++
+}
+
+func (d *digestWriter) close() error {
+	return d.next.close()
+}
+
+// rawTime parses a raw JSON timestamp in any of the API's timestamp formats,
+// as recordTime does for decoded logs.
+func rawTime(raw json.RawMessage) (time.Time, bool) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return time.Time{}, false
+	}
+
+	if raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return time.Time{}, false
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		return t, err == nil
+	}
+
+	return recordTime(LogRecord{TimestampField: json.Number(raw)}, TimestampField)
+}
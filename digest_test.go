@@ -0,0 +1,41 @@
+package logshare
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDigestAfterRecordStage(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i, host := range []string{"a.example.com", "b.example.com", "a.example.com"} {
+			fmt.Fprintf(w, "{\"ClientRequestHost\":%q,\"EdgeResponseStatus\":%d,\"EdgeStartTimestamp\":%d,\"RayID\":\"%016x\"}\n", host, 200+i, 10+i, i)
+		}
+	})
+	c, srv := newTestClient(t, handler, &Options{
+		HostFilter:   "a.example.com",
+		RenameFields: map[string]string{StatusField: "status"},
+		Dest:         &bytes.Buffer{},
+	})
+	defer srv.Close()
+
+	start := time.Now().Add(-time.Hour).Unix()
+	meta, err := c.GetDigestFromTimestamp(context.Background(), testZoneID, start, start+60, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &PullDigest{
+		Count:        2,
+		StatusCodes:  map[int]int{200: 1, 202: 1},
+		MinTimestamp: time.Unix(10, 0),
+		MaxTimestamp: time.Unix(12, 0),
+	}
+	if !reflect.DeepEqual(meta.Digest, want) {
+		t.Errorf("Digest = %+v, want %+v", meta.Digest, want)
+	}
+}
@@ -0,0 +1,62 @@
+package logshare
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// WindowOverlap is the result of CheckDisjoint.
+type WindowOverlap struct {
+	// The number of logs in each window.
+	CountA int
+	CountB int
+	// The RayIDs found in both windows, sorted. Empty if the windows are
+	// disjoint.
+	Overlap []string
+	// Whether either window reached Options.MaxIndexedRecords, in which case
+	// some of its logs were not compared.
+	Incomplete bool
+}
+
+// Disjoint reports whether no log was found in both windows.
+func (w *WindowOverlap) Disjoint() bool {
+	return len(w.Overlap) == 0
+}
+
+// CheckDisjoint fetches the logs of two windows, typically adjacent ones, and
+// reports the RayIDs found in both. It is a diagnostic for the boundary
+// semantics of the endpoint in use (see Options.ByReceived), for checking
+// that chunked or followed pulls neither overlap nor need deduplication.
+// The logs are held in memory (see GetIndexedByRayID), so keep the windows
+// small; RayID must be in Fields, if Fields are set.
+func (c *Client) CheckDisjoint(ctx context.Context, zoneID string, a TimeRange, b TimeRange) (*WindowOverlap, error) {
+	if len(c.fields) > 0 && !hasField(c.fields, "RayID") {
+		return nil, errors.New("RayID must be in Fields to compare windows")
+	}
+
+	ia, ma, err := c.GetIndexedByRayID(ctx, zoneID, a.Start.Unix(), a.End.Unix(), 0)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch window %s", a)
+	}
+
+	ib, mb, err := c.GetIndexedByRayID(ctx, zoneID, b.Start.Unix(), b.End.Unix(), 0)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch window %s", b)
+	}
+
+	w := &WindowOverlap{
+		CountA:     len(ia),
+		CountB:     len(ib),
+		Incomplete: ma.IndexFull || mb.IndexFull,
+	}
+	for ray := range ia {
+		if _, ok := ib[ray]; ok {
+			w.Overlap = append(w.Overlap, ray)
+		}
+	}
+	sort.Strings(w.Overlap)
+
+	return w, nil
+}
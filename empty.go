@@ -0,0 +1,62 @@
+package logshare
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// DefaultEmptyMarkerLine is the line written by EmitEmptyMarker when no
+// EmptyMarkerLine is set.
+const DefaultEmptyMarkerLine = `{"logshare_empty":true}`
+
+// EmptyMarker can be implemented by a destination writer to record, in its
+// own way, that a pull returned no logs. With Options.EmitEmptyMarker set,
+// MarkEmpty is called instead of writing a marker line.
+type EmptyMarker interface {
+	MarkEmpty() error
+}
+
+// emptyMarker is implemented by logWriters that can mark an empty pull.
+type emptyMarker interface {
+	markEmpty(line string) error
+}
+
+// markEmpty marks an empty pull on lw, if it supports it.
+func markEmpty(lw logWriter, line string) error {
+	if m, ok := lw.(emptyMarker); ok {
+		return m.markEmpty(line)
+	}
+
+	return nil
+}
+
+func (n *ndjsonWriter) markEmpty(line string) error {
+	if m, ok := n.w.(EmptyMarker); ok {
+		return m.MarkEmpty()
+	}
+
+	_, err := io.WriteString(n.w, line+"\n")
+	return err
+}
+
+// MarkEmpty implements EmptyMarker by creating an empty (but valid) gzip file
+// and index, so that an empty pull still leaves a file behind.
+func (r *RotatingGzipWriter) MarkEmpty() error {
+	if r.file == nil {
+		if err := r.openFile(); err != nil {
+			return err
+		}
+	}
+
+	// Write an empty gzip member, as a file with no members is not valid
+	// gzip.
+	if r.cw.n == 0 {
+		gz := gzip.NewWriter(r.cw)
+		if err := gz.Close(); err != nil {
+			return err
+		}
+		r.blockStart = r.cw.n
+	}
+
+	return nil
+}
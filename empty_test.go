@@ -0,0 +1,42 @@
+package logshare
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNoContentFinishesOutput(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{name: "JSON array", opts: Options{Format: FormatJSONArray}, want: "[]\n"},
+		{name: "CSV header", opts: Options{Format: FormatCSV, EmitHeader: true, Fields: []string{"RayID"}}, want: "RayID\n"},
+		{name: "buffered", opts: Options{EmitEmptyMarker: true, WriteBufferLines: 10}, want: "{\"logshare_empty\":true}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			var out bytes.Buffer
+			tt.opts.Dest = &out
+			c, srv := newTestClient(t, handler, &tt.opts)
+			defer srv.Close()
+
+			start := time.Now().Add(-time.Hour).Unix()
+			meta, _ := c.GetFromTimestamp(testZoneID, start, start+60, 0)
+			if meta == nil || !meta.Empty {
+				t.Fatalf("meta = %+v, want an empty pull", meta)
+			}
+			if out.String() != tt.want {
+				t.Errorf("wrote %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package logshare
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Defaults for Options.RetryOnEmpty.
+const (
+	DefaultRetryOnEmptyDelay  = 5 * time.Second
+	DefaultRetryOnEmptyWindow = 5 * time.Minute
+)
+
+// errRetryEmpty is returned by requestOnce, before anything is written, when
+// an empty response should be retried.
+var errRetryEmpty = errors.New("empty response")
+
+// request makes a request for u, streaming the logs to lw. Empty responses
+// and connection errors are retried as configured by Options.RetryOnEmpty and
+// Options.ConnRetries, all within Options.MaxWallClock, which applies from
+// here unless the caller has already applied it.
+func (c *Client) request(ctx context.Context, u *url.URL, lw logWriter) (*Meta, error) {
+	ctx, cancel := c.withWallClock(ctx)
+	defer cancel()
+
+	retries, connRetries := 0, 0
+	for {
+		meta, err := c.requestOnce(ctx, u, lw, c.retryEmpty(u, retries))
+
+		delay := c.retryOnEmptyDelay
+		if ce, ok := err.(*connError); ok {
+			if connRetries >= c.connRetries {
+				return partial(ctx, meta, errors.Wrapf(ce.err, "after %d connection retries", connRetries))
+			}
+			delay = c.connRetryDelay(connRetries)
+			connRetries++
+			c.metrics.IncRetries()
+		} else if err == errRetryEmpty {
+			retries++
+			c.metrics.IncRetries()
+		} else {
+			if meta != nil {
+				meta.EmptyRetries = retries
+				meta.ConnRetries = connRetries
+			}
+			return partial(ctx, meta, err)
+		}
+
+		if err := sleepContext(ctx, delay); err != nil {
+			return partial(ctx, meta, err)
+		}
+	}
+}
+
+// retryEmpty reports whether an empty response to u should be retried, after
+// the given number of retries. Only windows that end within
+// RetryOnEmptyWindow of the latest available logs are retried, as those are
+// the ones that may not have been fully propagated yet.
+func (c *Client) retryEmpty(u *url.URL, retries int) bool {
+	if retries >= c.retryOnEmpty {
+		return false
+	}
+
+	end, err := strconv.ParseInt(u.Query().Get(c.endParam()), 10, 64)
+	if err != nil {
+		return false
+	}
+
+	boundary := c.availableEnd().Add(-c.retryOnEmptyWindow)
+	return time.Unix(unixSeconds(end), 0).After(boundary)
+}
+
+// emptyBody reports whether the body of a response is empty, returning a
+// reader for the whole body.
+func emptyBody(body io.Reader) (io.Reader, bool) {
+	br := bufio.NewReader(body)
+	_, err := br.Peek(1)
+	return br, err == io.EOF
+}
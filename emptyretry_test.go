@@ -0,0 +1,43 @@
+package logshare
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryOnEmpty(t *testing.T) {
+	requests := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= 2 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte(testLogs(1)))
+	})
+
+	var out bytes.Buffer
+	metrics := &retryMetrics{}
+	c, srv := newTestClient(t, handler, &Options{
+		RetryOnEmpty:      3,
+		RetryOnEmptyDelay: time.Millisecond,
+		Metrics:           metrics,
+		Dest:              &out,
+	})
+	defer srv.Close()
+
+	end := c.availableEnd().Unix()
+	meta, err := c.GetFromTimestamp(testZoneID, end-60, end, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if meta.EmptyRetries != 2 || out.String() != testLogs(1) {
+		t.Errorf("EmptyRetries = %d, wrote %q", meta.EmptyRetries, out.String())
+	}
+	if metrics.retries != 2 {
+		t.Errorf("counted %d retries, want 2", metrics.retries)
+	}
+}
@@ -0,0 +1,62 @@
+package logshare
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// EnvelopeFunc wraps a log in a caller-defined envelope, such as one tagging
+// it with the zone and window it was pulled from, before it is written. meta
+// describes the request the log came from; only the fields known before the
+// logs are read (such as URL, StatusCode, RequestID and CFRay) are set, and
+// it must not be modified.
+type EnvelopeFunc func(record LogRecord, meta *Meta) interface{}
+
+// metaBinder is implemented by logWriters that use the Meta of the request
+// they are writing.
+type metaBinder interface {
+	bindMeta(meta Meta)
+}
+
+// bindMeta passes a copy of meta, as it is before the logs are read, to lw if
+// it uses it.
+func bindMeta(lw logWriter, meta *Meta) {
+	if b, ok := lw.(metaBinder); ok {
+		b.bindMeta(*meta)
+	}
+}
+
+// envelopeWriter is a logWriter that wraps each log with an EnvelopeFunc and
+// passes the envelope on as JSON.
+type envelopeWriter struct {
+	forwarder
+	fn   EnvelopeFunc
+	meta Meta
+}
+
+func (e *envelopeWriter) writeLog(line []byte) error {
+	rec, err := decodeLog(line)
+	if err != nil {
+		return err
+	}
+
+	return e.writeRecord(rec)
+}
+
+func (e *envelopeWriter) writeRecord(rec LogRecord) error {
+	b, err := json.Marshal(e.fn(rec, &e.meta))
+	if err != nil {
+		return errors.Wrap(err, "failed to encode envelope")
+	}
+
+	return e.next.writeLog(b)
+}
+
+func (e *envelopeWriter) close() error {
+	return e.next.close()
+}
+
+func (e *envelopeWriter) bindMeta(meta Meta) {
+	e.meta = meta
+}
@@ -0,0 +1,80 @@
+package logshare
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// EstimateBytes estimates the size of the logs between start and end by
+// fetching a sample of them. sampleRate is the fraction of logs to fetch,
+// from 0.001 to 1, and overrides Options.Sample.
+//
+// The estimate is the size of the logs as returned by the API, including a
+// newline after each: options that change the output, such as Format or
+// AddIngestField, are not accounted for. Meta.EstimateMargin holds the
+// margin of error of the estimate at 95% confidence, which narrows as the
+// sample grows; a sample of a few hundred logs is usually enough to be within
+// a few percent. Meta.Count is the number of logs sampled. A sample that
+// reaches the response cap (see Options.ResponseCap) fails, as it may not
+// cover the whole window.
+func (c *Client) EstimateBytes(ctx context.Context, zoneID string, start time.Time, end time.Time, sampleRate float64) (int64, *Meta, error) {
+	if sampleRate < 0.001 || sampleRate > 1 {
+		return 0, nil, errors.New("sampleRate must be between 0.001 and 1")
+	}
+
+	if !end.After(start) {
+		return 0, nil, errors.New("end must be after start")
+	}
+
+	u, err := c.timestampURL(ctx, zoneID, start.Unix(), end.Unix(), 0)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	params := u.Query()
+	p := effectiveSample(sampleRate)
+	params.Set("sample", strconv.FormatFloat(p, 'f', 3, 64))
+	u.RawQuery = params.Encode()
+
+	sc := &sizeCounter{}
+	meta, err := c.request(ctx, u, sc)
+	if meta != nil {
+		meta.SampleRate = p
+	}
+	if meta != nil && meta.StatusCode == http.StatusNoContent {
+		return 0, meta, nil
+	}
+	if err != nil {
+		return 0, meta, err
+	}
+	if meta.CapReached {
+		return 0, meta, errors.Errorf("the sample of %d logs reached the response cap, so the rest of the window is unknown: use a lower sampleRate or a shorter window", meta.Count)
+	}
+
+	// Each log is sampled independently with probability p, so scaling the
+	// sampled total by 1/p estimates the full total, with a variance of
+	// (1-p)/p² times the sum of the squared sizes.
+	meta.EstimateMargin = int64(1.96 * math.Sqrt((1-p)/(p*p)*sc.squares))
+
+	return int64(float64(sc.total) / p), meta, nil
+}
+
+// sizeCounter is a logWriter that measures the logs written to it.
+type sizeCounter struct {
+	total   int64
+	squares float64
+}
+
+func (s *sizeCounter) writeLog(line []byte) error {
+	n := int64(len(line)) + 1
+	s.total += n
+	s.squares += float64(n) * float64(n)
+	return nil
+}
+
+func (s *sizeCounter) close() error { return nil }
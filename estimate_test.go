@@ -0,0 +1,46 @@
+package logshare
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEstimateBytesCapReached(t *testing.T) {
+	tests := []struct {
+		name string
+		logs int
+		want string
+	}{
+		{name: "below the cap", logs: 2},
+		{name: "at the cap", logs: 3, want: "reached the response cap"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, testLogs(tt.logs))
+			})
+			c, srv := newTestClient(t, handler, &Options{ResponseCap: 3})
+			defer srv.Close()
+
+			end := time.Now().Add(-time.Hour)
+			n, _, err := c.EstimateBytes(context.Background(), testZoneID, end.Add(-time.Minute), end, 0.5)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := int64(2 * len(testLogs(tt.logs))); n != want {
+					t.Errorf("estimated %d bytes, want %d", n, want)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
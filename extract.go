@@ -0,0 +1,129 @@
+package logshare
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// ExtractField fetches logs between the start and end timestamps (up to
+// 'count' logs) and writes only the value of field, one per line, to w, after
+// any record-level options (such as HostFilter or Redactions). Only that
+// field is requested from the API, unless those options need others. Logs
+// where the field is missing or null are skipped. Values are written as in
+// FormatCSV cells: strings and numbers as-is, objects and arrays as JSON.
+// Meta.Extracted counts the values written.
+func (c *Client) ExtractField(ctx context.Context, zoneID string, start int64, end int64, count int, field string, w io.Writer) (*Meta, error) {
+	return c.extractField(ctx, zoneID, start, end, count, field, 0, w)
+}
+
+// ExtractUniqueField is like ExtractField, but writes each value once. To
+// bound memory, at most maxUnique values are remembered: once that many have
+// been seen, new values are written every time they occur. Meta.Deduplicated
+// counts the repeated values that were skipped.
+func (c *Client) ExtractUniqueField(ctx context.Context, zoneID string, start int64, end int64, count int, field string, maxUnique int, w io.Writer) (*Meta, error) {
+	if maxUnique <= 0 {
+		return nil, errors.New("maxUnique must be greater than zero")
+	}
+
+	return c.extractField(ctx, zoneID, start, end, count, field, maxUnique, w)
+}
+
+func (c *Client) extractField(ctx context.Context, zoneID string, start int64, end int64, count int, field string, maxUnique int, w io.Writer) (*Meta, error) {
+	if field == "" {
+		return nil, errors.New("field cannot be empty")
+	}
+
+	ew := &extractWriter{w: bufio.NewWriter(w), field: field, max: maxUnique}
+	if maxUnique > 0 {
+		ew.seen = make(map[string]struct{})
+	}
+	lw := c.withRecordStage(ew)
+
+	nc := c.withSingleField(field, lw != logWriter(ew))
+	meta, err := nc.getFromTimestamp(ctx, zoneID, start, end, count, lw)
+	if meta != nil && meta.StatusCode == http.StatusNoContent {
+		err = nil
+	}
+
+	return meta, err
+}
+
+// withSingleField returns a copy of the client for a pull that only reads
+// field. Only that field is requested, unless the pull has a record stage,
+// whose options (such as HostFilter or Redactions) may need other fields; the
+// field is then added to Fields, if they are set.
+func (c *Client) withSingleField(field string, staged bool) *Client {
+	if !staged {
+		return c.withFields([]string{field})
+	}
+
+	if c.fields == nil || hasField(c.fields, field) {
+		return c
+	}
+
+	return c.withFields(append(c.fields[:len(c.fields):len(c.fields)], field))
+}
+
+// extractWriter is a logWriter that writes the value of a single field of each
+// log, optionally skipping values it has already written.
+type extractWriter struct {
+	w     *bufio.Writer
+	field string
+	max   int
+	seen  map[string]struct{}
+
+	extracted    int
+	deduplicated int
+}
+
+func (e *extractWriter) writeLog(line []byte) error {
+	var rec map[string]json.RawMessage
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return errors.Wrap(err, "failed to decode log")
+	}
+
+	raw, ok := rec[e.field]
+	if !ok {
+		return nil
+	}
+
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return errors.Wrap(err, "failed to decode field")
+	}
+	if v == nil {
+		return nil
+	}
+
+	s := csvValue(v)
+	if e.seen != nil {
+		if _, ok := e.seen[s]; ok {
+			e.deduplicated++
+			return nil
+		}
+		if len(e.seen) < e.max {
+			e.seen[s] = struct{}{}
+		}
+	}
+
+	e.extracted++
+	e.w.WriteString(s)
+	return e.w.WriteByte('\n')
+}
+
+func (e *extractWriter) close() error {
+	return e.w.Flush()
+}
+
+func (e *extractWriter) report(meta *Meta) {
+	meta.Extracted += e.extracted
+	meta.Deduplicated += e.deduplicated
+}
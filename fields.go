@@ -0,0 +1,213 @@
+package logshare
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// FetchFields fetches the available log fields of a zone, as a map of field
+// name to description, and stores them in the client's SchemaCache, if any.
+func (c *Client) FetchFields(ctx context.Context, zoneID string) (map[string]string, error) {
+	zoneID, err := c.resolveZone(zoneID)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := c.fieldsURL(zoneID)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if _, err := c.request(ctx, u, &ndjsonWriter{w: &buf}); err != nil {
+		return nil, err
+	}
+
+	var fields map[string]string
+	if err := json.Unmarshal(buf.Bytes(), &fields); err != nil {
+		return nil, errors.Wrap(err, "failed to decode field names")
+	}
+
+	if c.schemaCache != nil {
+		c.schemaCache.put(zoneID, fields)
+	}
+
+	return fields, nil
+}
+
+// NormalizeFields checks Options.Fields against the fields available for the
+// zone, correcting the casing of fields that only differ from an available
+// field by case. Field names are case-sensitive in the API, so a miscased
+// field would otherwise be silently empty.
+//
+// A warning is returned for each corrected field. If a field can't be matched
+// at all, an error is returned suggesting similarly named fields. Like other
+// client configuration, NormalizeFields must not be called concurrently with
+// requests.
+func (c *Client) NormalizeFields(ctx context.Context, zoneID string) ([]string, error) {
+	if len(c.fields) == 0 {
+		return nil, nil
+	}
+
+	schema, err := c.FetchFields(ctx, zoneID)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, warnings, err := normalizeFields(c.fields, schema)
+	if err != nil {
+		return warnings, err
+	}
+	c.fields = fields
+
+	return warnings, nil
+}
+
+func normalizeFields(fields []string, schema map[string]string) ([]string, []string, error) {
+	canonical := make(map[string]string, len(schema))
+	for name := range schema {
+		canonical[strings.ToLower(name)] = name
+	}
+
+	var warnings, unknown []string
+	out := make([]string, len(fields))
+	for i, f := range fields {
+		if _, ok := schema[f]; ok {
+			out[i] = f
+			continue
+		}
+
+		if name, ok := canonical[strings.ToLower(f)]; ok {
+			warnings = append(warnings, fmt.Sprintf("field %q corrected to %q", f, name))
+			out[i] = name
+			continue
+		}
+
+		msg := fmt.Sprintf("%q", f)
+		if s := suggestFields(f, schema); len(s) > 0 {
+			msg += fmt.Sprintf(" (did you mean %s?)", strings.Join(s, ", "))
+		}
+		unknown = append(unknown, msg)
+	}
+
+	if len(unknown) > 0 {
+		return nil, warnings, errors.Errorf("unknown fields: %s", strings.Join(unknown, "; "))
+	}
+
+	return out, warnings, nil
+}
+
+// suggestFields returns up to three available fields with names similar to
+// field.
+func suggestFields(field string, schema map[string]string) []string {
+	type candidate struct {
+		name string
+		dist int
+	}
+
+	lower := strings.ToLower(field)
+	var candidates []candidate
+	for name := range schema {
+		n := strings.ToLower(name)
+		d := editDistance(lower, n)
+		if strings.Contains(n, lower) || strings.Contains(lower, n) {
+			d = 0
+		}
+		if d <= 3 {
+			candidates = append(candidates, candidate{name, d})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].dist != candidates[j].dist {
+			return candidates[i].dist < candidates[j].dist
+		}
+		return candidates[i].name < candidates[j].name
+	})
+
+	var names []string
+	for i := 0; i < len(candidates) && i < 3; i++ {
+		names = append(names, candidates[i].name)
+	}
+
+	return names
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
+
+func min3(a int, b int, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+
+	return a
+}
+
+// PullNewFields fetches the fields of the zone's logs that are not in
+// baseline, along with RayID to join them to logs pulled before, for the logs
+// between the start and end timestamps (up to 'count' logs). The new fields
+// are listed in Meta.NewFields; if there are none, nothing is pulled.
+// Options.Fields is ignored.
+func (c *Client) PullNewFields(ctx context.Context, zoneID string, start int64, end int64, count int, baseline []string) (*Meta, error) {
+	schema, err := c.FetchFields(ctx, zoneID)
+	if err != nil {
+		return nil, err
+	}
+
+	var added []string
+	for f := range schema {
+		if f != "RayID" && !hasField(baseline, f) {
+			added = append(added, f)
+		}
+	}
+	sort.Strings(added)
+
+	if len(added) == 0 {
+		return &Meta{}, nil
+	}
+
+	nc := c.withFields(append([]string{"RayID"}, added...))
+	meta, err := nc.getFromTimestamp(ctx, zoneID, start, end, count, nc.newLogWriter(nc.dest))
+	if meta != nil {
+		meta.NewFields = added
+	}
+
+	return meta, err
+}
+
+// withFields returns a clone of the client that requests the given fields.
+func (c *Client) withFields(fields []string) *Client {
+	nc := c.clone()
+	nc.fields = fields
+	return nc
+}
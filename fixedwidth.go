@@ -0,0 +1,122 @@
+package logshare
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// FixedWidthField is a column of FormatFixedWidth output.
+type FixedWidthField struct {
+	// The log field to write.
+	Name string
+	// The width of the column, in characters.
+	Width int
+	// Right-align the value within the column, rather than left-align.
+	AlignRight bool
+	// The character to pad short values with. Defaults to a space.
+	Pad rune
+}
+
+// fixedWidthWriter writes each log as a line of fixed-width columns. Values
+// longer than their column are truncated (and counted), shorter values are
+// padded.
+type fixedWidthWriter struct {
+	w         io.Writer
+	fields    []FixedWidthField
+	sep       string
+	buf       bytes.Buffer
+	truncated int
+	strict    bool
+}
+
+func (c *Client) newFixedWidthWriter(w io.Writer) (*fixedWidthWriter, error) {
+	if err := checkFixedWidth(c.fixedWidth); err != nil {
+		return nil, err
+	}
+
+	return &fixedWidthWriter{w: w, fields: c.fixedWidth, sep: c.fieldSeparator, strict: c.missingFields == MissingError}, nil
+}
+
+// checkFixedWidth checks that there are FixedWidthFields, each with a
+// positive width.
+func checkFixedWidth(fields []FixedWidthField) error {
+	if len(fields) == 0 {
+		return errors.New("FixedWidthFields must be set for FormatFixedWidth")
+	}
+
+	for _, f := range fields {
+		if f.Width <= 0 {
+			return errors.Errorf("fixed-width field %q must have a positive width", f.Name)
+		}
+	}
+
+	return nil
+}
+
+func (f *fixedWidthWriter) writeLog(line []byte) error {
+	rec, err := decodeLog(line)
+	if err != nil {
+		return err
+	}
+
+	return f.writeRecord(rec)
+}
+
+func (f *fixedWidthWriter) writeRecord(rec LogRecord) error {
+	f.buf.Reset()
+	for i, field := range f.fields {
+		if i > 0 {
+			f.buf.WriteString(f.sep)
+		}
+		v, ok := rec[field.Name]
+		if !ok && f.strict {
+			return missingField(field.Name)
+		}
+		f.writeColumn(field, csvValue(v))
+	}
+	f.buf.WriteByte('\n')
+
+	_, err := f.w.Write(f.buf.Bytes())
+	return err
+}
+
+func (f *fixedWidthWriter) writeColumn(field FixedWidthField, v string) {
+	n := utf8.RuneCountInString(v)
+	if n > field.Width {
+		f.truncated++
+		runes := 0
+		for i := range v {
+			if runes == field.Width {
+				v = v[:i]
+				break
+			}
+			runes++
+		}
+		f.buf.WriteString(v)
+		return
+	}
+
+	pad := field.Pad
+	if pad == 0 {
+		pad = ' '
+	}
+	padding := strings.Repeat(string(pad), field.Width-n)
+
+	if field.AlignRight {
+		f.buf.WriteString(padding)
+		f.buf.WriteString(v)
+	} else {
+		f.buf.WriteString(v)
+		f.buf.WriteString(padding)
+	}
+}
+
+func (f *fixedWidthWriter) close() error { return nil }
+
+func (f *fixedWidthWriter) report(meta *Meta) {
+	meta.Truncated += f.truncated
+}
@@ -0,0 +1,106 @@
+package logshare
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// ArrayPolicy controls how FlattenNested handles arrays.
+type ArrayPolicy int
+
+const (
+	// ArrayJSON keeps arrays as a single JSON-encoded string value. This is
+	// the default.
+	ArrayJSON ArrayPolicy = iota
+	// ArrayIndex flattens arrays into one key per element, such as
+	// "Hosts.0" and "Hosts.1".
+	ArrayIndex
+)
+
+// flattenRecord returns a recordFunc that flattens nested objects into dotted
+// keys, e.g. {"RequestHeaders": {"Host": "a"}} into {"RequestHeaders.Host":
+// "a"}. Objects nested deeper than maxDepth (if non-zero) are kept as
+// JSON-encoded strings. A flattened key that collides with an existing one
+// (such as a top-level "RequestHeaders.Host" field) is handled per policy.
+func flattenRecord(arrays ArrayPolicy, maxDepth int, policy DuplicatePolicy) recordFunc {
+	return func(rec LogRecord) (LogRecord, error) {
+		f := flattener{out: make(LogRecord, len(rec)), arrays: arrays, maxDepth: maxDepth, policy: policy}
+
+		// Copy the top-level scalars first, so that they keep their names
+		// when a flattened key collides with them.
+		for k, v := range rec {
+			if !isNested(v) {
+				f.out[k] = v
+			}
+		}
+
+		for k, v := range rec {
+			if isNested(v) {
+				if err := f.flatten(k, v, 1); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		return f.out, nil
+	}
+}
+
+type flattener struct {
+	out      LogRecord
+	arrays   ArrayPolicy
+	maxDepth int
+	policy   DuplicatePolicy
+}
+
+func (f *flattener) flatten(key string, v interface{}, depth int) error {
+	if f.maxDepth > 0 && depth > f.maxDepth {
+		return setField(f.out, key, jsonString(v), f.policy)
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, vv := range v {
+			if err := f.flatten(key+"."+k, vv, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []interface{}:
+		if f.arrays != ArrayIndex {
+			return setField(f.out, key, jsonString(v), f.policy)
+		}
+		for i, vv := range v {
+			if err := f.flatten(key+"."+strconv.Itoa(i), vv, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return setField(f.out, key, v, f.policy)
+}
+
+func isNested(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return true
+	}
+
+	return false
+}
+
+// jsonString encodes objects and arrays as a JSON string, leaving other values
+// as they are.
+func jsonString(v interface{}) interface{} {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil
+		}
+		return string(b)
+	}
+
+	return v
+}
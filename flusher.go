@@ -0,0 +1,137 @@
+package logshare
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// DefaultFlushEvery is how many logs are written between flushes of an
+// http.Flusher destination when no Options.FlushEvery is set.
+const DefaultFlushEvery = 100
+
+// withFlusher wraps lw in an httpFlushWriter if w is an http.Flusher, such as
+// an http.ResponseWriter, so that logs are streamed to the client as they are
+// read rather than when the server's buffer fills.
+func (c *Client) withFlusher(w io.Writer, lw logWriter) logWriter {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		return lw
+	}
+
+	every := c.flushEvery
+	if every <= 0 {
+		every = DefaultFlushEvery
+	}
+
+	return &httpFlushWriter{forwarder: forwarder{next: lw}, f: f, every: every}
+}
+
+// httpFlushWriter is a logWriter that flushes its destination every so many
+// logs, and once the logs have been written.
+type httpFlushWriter struct {
+	forwarder
+	f     http.Flusher
+	every int
+	n     int
+}
+
+func (h *httpFlushWriter) writeLog(line []byte) error {
+	if err := h.next.writeLog(line); err != nil {
+		return err
+	}
+
+	return h.wrote()
+}
+
+func (h *httpFlushWriter) writeRecord(rec LogRecord) error {
+	if err := writeRecordTo(h.next, rec); err != nil {
+		return err
+	}
+
+	return h.wrote()
+}
+
+func (h *httpFlushWriter) wrote() error {
+	h.n++
+	if h.n%h.every == 0 {
+		if err := flushBuffer(h.next); err != nil {
+			return err
+		}
+		h.f.Flush()
+	}
+
+	return nil
+}
+
+func (h *httpFlushWriter) close() error {
+	err := h.next.close()
+	h.f.Flush()
+
+	return err
+}
+
+func (h *httpFlushWriter) markEmpty(line string) error {
+	err := markEmpty(h.next, line)
+	if ferr := flushBuffer(h.next); err == nil {
+		err = ferr
+	}
+	h.f.Flush()
+
+	return err
+}
+
+func (h *httpFlushWriter) writeSummary(line []byte) error {
+	err := writeSummaryTo(h.next, line)
+	h.f.Flush()
+
+	return err
+}
+
+// ServeLogs fetches the logs between the start and end timestamps (up to
+// 'count' logs) and streams them to w in the client's output format, as the
+// response to r, for serving logs from a web endpoint. The Content-Type is
+// set from the format, and responses are marked as not to be buffered or
+// cached by proxies; logs are flushed to the client every
+// Options.FlushEvery logs.
+//
+// If the pull fails before any logs are written, a 502 Bad Gateway response
+// is sent with the error. Once logs have been written, the status can no
+// longer be changed, and the response is cut short. Either way, the error is
+// returned. The request is cancelled if the client goes away.
+func (c *Client) ServeLogs(w http.ResponseWriter, r *http.Request, zoneID string, start int64, end int64, count int) (*Meta, error) {
+	h := w.Header()
+	h.Set("Content-Type", c.contentType())
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+
+	meta, err := c.getFromTimestamp(r.Context(), zoneID, start, end, count, c.newLogWriter(w))
+	if meta != nil && meta.StatusCode == http.StatusNoContent {
+		err = nil
+	}
+	if err != nil && (meta == nil || meta.Count == 0) && r.Context().Err() != context.Canceled {
+		h.Del("Cache-Control")
+		h.Del("X-Accel-Buffering")
+		http.Error(w, err.Error(), http.StatusBadGateway)
+	}
+
+	return meta, err
+}
+
+// contentType returns the MIME type of the client's output format.
+func (c *Client) contentType() string {
+	if c.codec != nil || c.format == FormatLengthPrefixed {
+		return "application/octet-stream"
+	}
+
+	switch c.format {
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatJSONArray:
+		return "application/json"
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	}
+
+	return "text/plain; charset=utf-8"
+}
@@ -0,0 +1,371 @@
+package logshare
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultFollowPollInterval is the default FollowOptions.PollInterval.
+const DefaultFollowPollInterval = 30 * time.Second
+
+// DefaultDedupKeyField is the default Options.DedupKeyField.
+const DefaultDedupKeyField = "RayID"
+
+// FollowOptions configures Follow. The zero value follows indefinitely using
+// the default lag and poll interval.
+type FollowOptions struct {
+	// How far behind the current time to stay, as logs are not available
+	// from the API immediately. Defaults to Options.AvailabilityLag.
+	Lag time.Duration
+	// How long to wait between polls. Defaults to DefaultFollowPollInterval.
+	PollInterval time.Duration
+	// The bounds of an adaptive poll interval. Starting from PollInterval,
+	// the interval is halved after a poll that returned logs and doubled
+	// after one that didn't, staying between MinPollInterval and
+	// MaxPollInterval. Intervals are measured between the starts of polls,
+	// so a busy zone is never polled more often than every MinPollInterval,
+	// however quickly a poll completes. Each defaults to PollInterval, which
+	// without either set keeps the interval fixed.
+	MinPollInterval time.Duration
+	MaxPollInterval time.Duration
+
+	// Stop conditions. Any of these ends Follow cleanly once reached. They are
+	// checked between polls, so a poll is always written in full and the
+	// totals may overshoot the limit by up to one poll.
+	MaxTotalRecords int
+	MaxTotalBytes   int64
+	MaxDuration     time.Duration
+
+	// How far back FollowResumable goes: where there is no checkpoint, and
+	// at most, however old the checkpoint. Defaults to
+	// Options.InitialLookback.
+	MaxLookback time.Duration
+}
+
+// Reasons Follow stopped, reported in Meta.StopReason.
+const (
+	StopMaxRecords = "max-records"
+	StopMaxBytes   = "max-bytes"
+	StopMaxTime    = "max-duration"
+)
+
+// Follow tails the logs of a zone from start, polling for new logs every
+// PollInterval (or adaptively, between MinPollInterval and MaxPollInterval)
+// and writing them to the client's destination.
+//
+// Each poll requests the window from the end of the last one up to Lag before
+// the current time, so the windows are contiguous whether or not a poll
+// returned logs, and logs arriving within Lag are not missed. If a poll is
+// cut short by Options.MaxWallClock, or reaches the response cap (see
+// Options.ResponseCap), the next poll resumes from the second of the last
+// log read, and logs in that second that were already written are skipped by
+// their Options.DedupKeyField. Only that second is deduplicated, which covers
+// the requests endpoint; under Options.ByReceived, logs are not in timestamp
+// order, and those of other seconds may be written again. With MaxWallClock
+// set, both EdgeStartTimestamp and the DedupKeyField must be in Fields, if
+// Fields are set; a poll that reaches the response cap without them fails,
+// as does one whose logs all fall in the second it started from.
+//
+// Follow runs until a stop condition in opts is met, the context is cancelled
+// or a request fails. The returned Meta summarizes every poll; Meta.StopReason
+// is set when a stop condition ended the session, in which case the error is
+// nil. The destination is flushed before returning if it has a Flush method.
+func (c *Client) Follow(ctx context.Context, zoneID string, start time.Time, opts *FollowOptions) (*Meta, error) {
+	return c.follow(ctx, zoneID, start, opts, nil)
+}
+
+// FollowResumable is Follow, resumed from the zone's cursor in checkpointer
+// and saving the cursor after each poll, once its logs have been written and
+// the destination flushed (if it has a Flush method). A restarted tailer
+// then carries on where the last one stopped. Logs written by a poll that
+// failed part way, or that were cut short by Options.MaxWallClock, may be
+// written again after a restart. The cursor saved after a poll that reached
+// the response cap is the second of its last log, as Follow resumes from.
+//
+// To bound the catch-up after a long downtime, Follow starts from at most
+// opts.MaxLookback before the latest available logs, skipping any older logs
+// since the checkpoint; Meta.LookbackSkipped reports how much was skipped.
+// Without a checkpoint, it starts from MaxLookback before the latest
+// available logs.
+func (c *Client) FollowResumable(ctx context.Context, zoneID string, checkpointer Checkpointer, opts *FollowOptions) (*Meta, error) {
+	if opts == nil {
+		opts = &FollowOptions{}
+	}
+
+	lookback := opts.MaxLookback
+	if lookback <= 0 {
+		lookback = c.initialLookback
+	}
+
+	start, ok, err := checkpointer.LoadCheckpoint(zoneID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load checkpoint")
+	}
+
+	var skipped time.Duration
+	earliest := c.now().Add(-opts.lag(c)).Add(-lookback).Truncate(time.Second)
+	if !ok || start.Before(earliest) {
+		if ok {
+			skipped = earliest.Sub(start)
+		}
+		start = earliest
+	}
+
+	meta, err := c.follow(ctx, zoneID, start, opts, func(cursor time.Time) error {
+		return errors.Wrap(checkpointer.SaveCheckpoint(zoneID, cursor), "failed to save checkpoint")
+	})
+	if meta != nil {
+		meta.LookbackSkipped = skipped
+	}
+
+	return meta, err
+}
+
+// follow is Follow, calling polled (if set) with the cursor after each poll
+// has been written and the destination flushed.
+func (c *Client) follow(ctx context.Context, zoneID string, start time.Time, opts *FollowOptions, polled func(cursor time.Time) error) (*Meta, error) {
+	if c.reverse {
+		return nil, errors.New("Reverse cannot be used with Follow")
+	}
+
+	if c.maxWallClock > 0 && c.fields != nil {
+		for _, f := range []string{TimestampField, c.dedupKey} {
+			if !hasField(c.fields, f) {
+				return nil, errors.Errorf("%s must be in Fields to follow with MaxWallClock", f)
+			}
+		}
+	}
+
+	if opts == nil {
+		opts = &FollowOptions{}
+	}
+
+	lag := opts.lag(c)
+
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = DefaultFollowPollInterval
+	}
+
+	poll := newPollInterval(interval, opts.MinPollInterval, opts.MaxPollInterval)
+
+	began := time.Now()
+	cursor := start.Unix()
+	total := &Meta{}
+	boundary := &boundaryWriter{key: c.dedupKey}
+
+	var deadline <-chan time.Time
+	if opts.MaxDuration > 0 {
+		timer := time.NewTimer(opts.MaxDuration)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	for {
+		pollStart := time.Now()
+		busy := false
+		end := c.now().Add(-lag).Unix()
+		if end > cursor {
+			u, err := c.timestampURL(ctx, zoneID, cursor, end, 0)
+			if err != nil {
+				return total, err
+			}
+
+			boundary.next = c.newLogWriter(c.dest)
+			meta, err := c.request(ctx, u, boundary)
+			if err != nil && meta != nil && meta.StatusCode == http.StatusNoContent {
+				// An empty window is expected when tailing a quiet zone.
+				err = nil
+			}
+			total.add(meta)
+			total.Polls++
+			if err != nil {
+				flushWriter(c.dest)
+				return total, err
+			}
+			boundary.endPoll()
+
+			busy = meta.Count > 0
+			switch {
+			case !meta.Partial && !meta.CapReached:
+				cursor = end
+			case !meta.MaxTimestamp.IsZero() && (meta.Partial || meta.MaxTimestamp.Unix() > cursor):
+				// Resume from the second of the last log read, whose logs
+				// were deduplicated against this poll's.
+				cursor = meta.MaxTimestamp.Unix()
+				busy = true
+			case meta.CapReached:
+				// Resuming from the same cursor would read the same logs.
+				flushWriter(c.dest)
+				return total, errors.Errorf("a poll from %d reached the response cap, and the logs after it can't be resumed from: %s must be in Fields, and there must be no more than ResponseCap logs in one second", cursor, TimestampField)
+			}
+
+			if polled != nil {
+				if err := flushWriter(c.dest); err != nil {
+					return total, errors.Wrap(err, "failed to flush logs")
+				}
+				if err := polled(time.Unix(cursor, 0)); err != nil {
+					return total, err
+				}
+			}
+		}
+
+		switch {
+		case opts.MaxTotalRecords > 0 && total.Count >= opts.MaxTotalRecords:
+			total.StopReason = StopMaxRecords
+		case opts.MaxTotalBytes > 0 && total.Bytes >= opts.MaxTotalBytes:
+			total.StopReason = StopMaxBytes
+		case opts.MaxDuration > 0 && time.Since(began) >= opts.MaxDuration:
+			total.StopReason = StopMaxTime
+		}
+
+		if total.StopReason == "" {
+			select {
+			case <-ctx.Done():
+				flushWriter(c.dest)
+				return total, ctx.Err()
+			case <-deadline:
+				total.StopReason = StopMaxTime
+			case <-time.After(poll.next(busy) - time.Since(pollStart)):
+				continue
+			}
+		}
+
+		return total, flushWriter(c.dest)
+	}
+}
+
+// lag returns how far behind the current time to follow.
+func (o *FollowOptions) lag(c *Client) time.Duration {
+	if o.Lag > 0 {
+		return o.Lag
+	}
+
+	return c.availabilityLag
+}
+
+// boundaryWriter is a logWriter that passes the logs of each Follow poll to
+// next, skipping those the previous poll already wrote. It tracks the keys
+// of the logs in the latest second of a poll, which is where the next poll
+// resumes after a partial poll. That covers every repeated log on the
+// requests endpoint, whose logs are in timestamp order; under
+// Options.ByReceived, logs of other seconds may be read again, and are not
+// skipped.
+type boundaryWriter struct {
+	forwarder
+	// The field identifying a log (see Options.DedupKeyField).
+	key string
+
+	// The latest second of this poll, and the keys of the logs in it.
+	sec  int64
+	rays map[string]bool
+	// The same, for the previous poll.
+	prevSec  int64
+	prevRays map[string]bool
+
+	skipped int
+}
+
+func (b *boundaryWriter) writeLog(line []byte) error {
+	t, ok := lineTime(line)
+	if !ok {
+		return b.next.writeLog(line)
+	}
+
+	s := t.Unix()
+	key := lineValue(line, b.key)
+
+	// Track skipped logs too: if this poll is also cut short in the same
+	// second, the next poll must skip them again.
+	if s > b.sec {
+		b.sec = s
+		b.rays = make(map[string]bool)
+	}
+	if s == b.sec && key != nil {
+		b.rays[string(key)] = true
+	}
+
+	if s == b.prevSec && key != nil && b.prevRays[string(key)] {
+		b.skipped++
+		return nil
+	}
+
+	return b.next.writeLog(line)
+}
+
+// endPoll makes the boundary of the finished poll the one to deduplicate the
+// next poll against. An empty poll keeps the previous boundary.
+func (b *boundaryWriter) endPoll() {
+	if b.rays != nil {
+		b.prevSec, b.prevRays = b.sec, b.rays
+	}
+	b.sec, b.rays = 0, nil
+}
+
+func (b *boundaryWriter) close() error {
+	return b.next.close()
+}
+
+func (b *boundaryWriter) report(meta *Meta) {
+	meta.Duplicates += b.skipped
+	b.skipped = 0
+	reportTo(b.next, meta)
+}
+
+// pollInterval adapts the wait between Follow polls to how busy the zone is.
+type pollInterval struct {
+	cur, min, max time.Duration
+}
+
+func newPollInterval(interval time.Duration, min time.Duration, max time.Duration) *pollInterval {
+	if min <= 0 {
+		min = interval
+	}
+	if max <= 0 {
+		max = interval
+	}
+	if max < min {
+		max = min
+	}
+
+	p := &pollInterval{cur: interval, min: min, max: max}
+	p.clamp()
+
+	return p
+}
+
+// next returns the interval until the next poll, given whether the last poll
+// returned any logs.
+func (p *pollInterval) next(busy bool) time.Duration {
+	if busy {
+		p.cur /= 2
+	} else {
+		p.cur *= 2
+	}
+	p.clamp()
+
+	return p.cur
+}
+
+func (p *pollInterval) clamp() {
+	if p.cur < p.min {
+		p.cur = p.min
+	}
+	if p.cur > p.max {
+		p.cur = p.max
+	}
+}
+
+// flushWriter flushes w if it buffers writes, such as a *bufio.Writer.
+func flushWriter(w interface{}) error {
+	if f, ok := w.(interface {
+		Flush() error
+	}); ok {
+		return f.Flush()
+	}
+
+	return nil
+}
@@ -0,0 +1,330 @@
+package logshare
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testLog(ray string, sec int64) string {
+	return fmt.Sprintf(`{"EdgeStartTimestamp":%d,"RayID":%q}`, sec, ray)
+}
+
+// rayIDs returns the RayIDs of the NDJSON logs in b, in order.
+func rayIDs(t *testing.T, b []byte) []string {
+	t.Helper()
+
+	var rays []string
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		if line == "" {
+			continue
+		}
+		var rec struct{ RayID string }
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatal(err)
+		}
+		rays = append(rays, rec.RayID)
+	}
+
+	return rays
+}
+
+func TestBoundaryWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		polls   [][]string
+		want    []string
+		skipped int
+	}{
+		{
+			name: "contiguous polls",
+			polls: [][]string{
+				{testLog("a", 10), testLog("b", 11)},
+				{testLog("c", 12)},
+			},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "resumed poll",
+			polls: [][]string{
+				{testLog("a", 10), testLog("b", 11)},
+				{testLog("b", 11), testLog("c", 12)},
+			},
+			want:    []string{"a", "b", "c"},
+			skipped: 1,
+		},
+		{
+			name: "empty poll keeps the boundary",
+			polls: [][]string{
+				{testLog("a", 10), testLog("b", 10)},
+				{},
+				{testLog("a", 10), testLog("b", 10), testLog("c", 11)},
+			},
+			want:    []string{"a", "b", "c"},
+			skipped: 2,
+		},
+		{
+			name: "late log in the boundary second",
+			polls: [][]string{
+				{testLog("a", 10)},
+				{testLog("a", 10), testLog("b", 10), testLog("c", 11)},
+			},
+			want:    []string{"a", "b", "c"},
+			skipped: 1,
+		},
+		{
+			name: "consecutive partial polls in one second",
+			polls: [][]string{
+				{testLog("a", 10), testLog("b", 10)},
+				{testLog("a", 10), testLog("b", 10), testLog("c", 10)},
+				{testLog("a", 10), testLog("b", 10), testLog("c", 10), testLog("d", 11)},
+			},
+			want:    []string{"a", "b", "c", "d"},
+			skipped: 5,
+		},
+		{
+			name: "burst over several seconds",
+			polls: [][]string{
+				{testLog("a", 10), testLog("b", 11), testLog("c", 11), testLog("d", 11)},
+				{testLog("b", 11), testLog("c", 11), testLog("d", 11), testLog("e", 11), testLog("f", 12)},
+			},
+			want:    []string{"a", "b", "c", "d", "e", "f"},
+			skipped: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			b := &boundaryWriter{forwarder: forwarder{next: &ndjsonWriter{w: &buf}}, key: DefaultDedupKeyField}
+			for _, poll := range tt.polls {
+				for _, line := range poll {
+					if err := b.writeLog([]byte(line)); err != nil {
+						t.Fatal(err)
+					}
+				}
+				b.endPoll()
+			}
+
+			if got := rayIDs(t, buf.Bytes()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wrote %v, want %v", got, tt.want)
+			}
+			if b.skipped != tt.skipped {
+				t.Errorf("skipped %d, want %d", b.skipped, tt.skipped)
+			}
+		})
+	}
+}
+
+func TestPollInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		min, max time.Duration
+		busy     []bool
+		want     []time.Duration
+	}{
+		{
+			name:     "fixed",
+			interval: 4 * time.Second,
+			busy:     []bool{true, false, false},
+			want:     []time.Duration{4 * time.Second, 4 * time.Second, 4 * time.Second},
+		},
+		{
+			name:     "busy halves down to min",
+			interval: 8 * time.Second,
+			min:      2 * time.Second,
+			max:      8 * time.Second,
+			busy:     []bool{true, true, true},
+			want:     []time.Duration{4 * time.Second, 2 * time.Second, 2 * time.Second},
+		},
+		{
+			name:     "idle doubles up to max",
+			interval: 2 * time.Second,
+			min:      time.Second,
+			max:      6 * time.Second,
+			busy:     []bool{false, false, false},
+			want:     []time.Duration{4 * time.Second, 6 * time.Second, 6 * time.Second},
+		},
+		{
+			name:     "burst after idle",
+			interval: 4 * time.Second,
+			min:      time.Second,
+			max:      16 * time.Second,
+			busy:     []bool{false, false, true, true, false},
+			want:     []time.Duration{8 * time.Second, 16 * time.Second, 8 * time.Second, 4 * time.Second, 8 * time.Second},
+		},
+		{
+			name:     "max below min",
+			interval: 4 * time.Second,
+			min:      3 * time.Second,
+			max:      time.Second,
+			busy:     []bool{false, true},
+			want:     []time.Duration{3 * time.Second, 3 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPollInterval(tt.interval, tt.min, tt.max)
+			var got []time.Duration
+			for _, busy := range tt.busy {
+				got = append(got, p.next(busy))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// followServer serves one response per poll from polls, and 204 No Content
+// once they run out. A response of nil also sends 204. Logs of a response
+// after a "" entry are held back until the request is cancelled, so that the
+// poll is cut short by MaxWallClock.
+type followServer struct {
+	polls [][]string
+
+	mu     sync.Mutex
+	starts []string
+}
+
+func (f *followServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	n := len(f.starts)
+	f.starts = append(f.starts, r.URL.Query().Get("start"))
+	f.mu.Unlock()
+
+	if n >= len(f.polls) || len(f.polls[n]) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	for _, line := range f.polls[n] {
+		if line == "" {
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+			return
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+func TestFollow(t *testing.T) {
+	sec := time.Now().Add(-time.Minute).Unix()
+
+	tests := []struct {
+		name       string
+		polls      [][]string
+		stopAfter  int
+		cap        int
+		want       []string
+		duplicates int
+		// The start of each poll after the first, relative to sec, or -1
+		// for the end of the previous poll.
+		resumes []int64
+	}{
+		{
+			name: "empty polls",
+			polls: [][]string{
+				nil,
+				nil,
+				{testLog("a", sec), testLog("b", sec+1)},
+			},
+			stopAfter: 2,
+			want:      []string{"a", "b"},
+			resumes:   []int64{-1, -1},
+		},
+		{
+			name: "partial poll",
+			polls: [][]string{
+				{testLog("a", sec), testLog("b", sec+1), ""},
+				{testLog("b", sec+1), testLog("c", sec+1), testLog("d", sec+2)},
+			},
+			stopAfter:  5,
+			want:       []string{"a", "b", "c", "d"},
+			duplicates: 1,
+			resumes:    []int64{1},
+		},
+		{
+			name: "consecutive partial polls in one second",
+			polls: [][]string{
+				{testLog("a", sec), testLog("b", sec), ""},
+				{testLog("a", sec), testLog("b", sec), testLog("c", sec), ""},
+				{testLog("a", sec), testLog("b", sec), testLog("c", sec), testLog("d", sec+1)},
+			},
+			stopAfter:  9,
+			want:       []string{"a", "b", "c", "d"},
+			duplicates: 5,
+			resumes:    []int64{0, 0},
+		},
+		{
+			name: "capped poll",
+			polls: [][]string{
+				{testLog("a", sec), testLog("b", sec+1), testLog("c", sec+1)},
+				{testLog("b", sec+1), testLog("c", sec+1), testLog("d", sec+2)},
+			},
+			stopAfter:  6,
+			cap:        3,
+			want:       []string{"a", "b", "c", "d"},
+			duplicates: 2,
+			resumes:    []int64{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			srv := &followServer{polls: tt.polls}
+			c, ts := newTestClient(t, srv, &Options{
+				Dest:         &buf,
+				Fields:       []string{TimestampField, "RayID"},
+				MaxWallClock: 200 * time.Millisecond,
+				ResponseCap:  tt.cap,
+			})
+			defer ts.Close()
+
+			start := time.Unix(sec, 0)
+			meta, err := c.Follow(context.Background(), testZoneID, start, &FollowOptions{
+				Lag:             time.Second,
+				PollInterval:    time.Millisecond,
+				MaxTotalRecords: tt.stopAfter,
+				MaxDuration:     10 * time.Second,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if meta.StopReason != StopMaxRecords {
+				t.Fatalf("stopped by %q", meta.StopReason)
+			}
+
+			if got := rayIDs(t, buf.Bytes()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wrote %v, want %v", got, tt.want)
+			}
+			if meta.Duplicates != tt.duplicates {
+				t.Errorf("Duplicates = %d, want %d", meta.Duplicates, tt.duplicates)
+			}
+
+			srv.mu.Lock()
+			defer srv.mu.Unlock()
+			if srv.starts[0] != fmt.Sprint(sec) {
+				t.Errorf("first poll started at %s, want %d", srv.starts[0], sec)
+			}
+			for i, resume := range tt.resumes {
+				if resume < 0 {
+					continue
+				}
+				if got, want := srv.starts[i+1], fmt.Sprint(sec+resume); got != want {
+					t.Errorf("poll %d started at %s, want %s", i+2, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,376 @@
+package logshare
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// OutputFormat selects how logs are written to the destination writer.
+type OutputFormat int
+
+const (
+	// FormatNDJSON writes each log as a line of JSON, exactly as returned by
+	// the API. This is the default.
+	FormatNDJSON OutputFormat = iota
+	// FormatJSONArray writes the logs as the elements of a single JSON array.
+	FormatJSONArray
+	// FormatCSV writes one CSV row per log. The columns are Options.Fields,
+	// or the sorted field names of the first log when no fields are set.
+	FormatCSV
+	// FormatPrettyJSON writes each log as indented JSON followed by a blank
+	// line, for reading in a terminal. The output is not valid NDJSON and is
+	// not intended for machine ingestion.
+	FormatPrettyJSON
+	// FormatFixedWidth writes each log as a line of fixed-width columns, as
+	// described by Options.FixedWidthFields and Options.FieldSeparator.
+	FormatFixedWidth
+	// FormatTemplate renders each log with the text/template in
+	// Options.Template, followed by a newline. Fields are referred to by
+	// name, as in "{{.ClientIP}} {{.ClientRequestURI}}". Values are written
+	// as-is, without escaping; the json function quotes a value as JSON,
+	// as in "{{json .ClientRequestUserAgent}}". A field that a log lacks (or
+	// that is null) is rendered as an empty string, or fails the pull under
+	// MissingError.
+	FormatTemplate
+	// FormatLengthPrefixed writes each log as a frame of its JSON preceded by
+	// its length in bytes, as a 4-byte big-endian integer, with no
+	// delimiters, for consumers that need exact log boundaries. Read the
+	// frames with a FrameReader.
+	FormatLengthPrefixed
+)
+
+// LogRecord is a single decoded log. Numbers are decoded as json.Number so
+// that large integers (e.g. nanosecond timestamps) are not rounded.
+type LogRecord map[string]interface{}
+
+// decodeLog decodes a single NDJSON log line. Invalid UTF-8 in string values
+// is replaced with U+FFFD by the decoder.
+func decodeLog(line []byte) (LogRecord, error) {
+	var rec LogRecord
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if err := dec.Decode(&rec); err != nil {
+		return nil, errors.Wrap(err, "failed to decode log")
+	}
+
+	return rec, nil
+}
+
+// logWriter writes logs to a destination in a specific OutputFormat.
+type logWriter interface {
+	// writeLog writes a single raw JSON log line (without the newline).
+	writeLog(line []byte) error
+	// close writes any trailing output, such as a closing bracket. It does not
+	// close the underlying writer.
+	close() error
+}
+
+// metaReporter is implemented by logWriters that keep counters to report in
+// the Meta of a request, once it has been streamed.
+type metaReporter interface {
+	report(meta *Meta)
+}
+
+// reportTo adds the counters of lw, if it has any, to meta.
+func reportTo(lw logWriter, meta *Meta) {
+	if r, ok := lw.(metaReporter); ok {
+		r.report(meta)
+	}
+}
+
+// forwarder passes the optional logWriter interfaces (metaReporter,
+// emptyMarker, summaryWriter, metaBinder, recordLimiter and bufferFlusher) on
+// to the next logWriter. A logWriter that wraps another embeds it, and only
+// implements those it needs to act on itself.
+type forwarder struct {
+	next logWriter
+}
+
+func (f forwarder) report(meta *Meta) {
+	reportTo(f.next, meta)
+}
+
+func (f forwarder) markEmpty(line string) error {
+	return markEmpty(f.next, line)
+}
+
+func (f forwarder) writeSummary(line []byte) error {
+	return writeSummaryTo(f.next, line)
+}
+
+func (f forwarder) bindMeta(meta Meta) {
+	bindMeta(f.next, &meta)
+}
+
+func (f forwarder) limitRecords(n int) {
+	limitRecords(f.next, n)
+}
+
+func (f forwarder) flushBuffer() error {
+	return flushBuffer(f.next)
+}
+
+// newLogWriter returns a logWriter for the client's configured codec or
+// format, applying any record-level transforms.
+func (c *Client) newLogWriter(w io.Writer) logWriter {
+	return c.withReverse(c.withReorder(c.withRecordStage(c.withSync(w, c.withFlusher(w, c.newBufferedFormatWriter(w))))))
+}
+
+// newSharedLogWriter is like newLogWriter, but numbers logs with seq.
+func (c *Client) newSharedLogWriter(w io.Writer, seq *sequencer) logWriter {
+	return c.withReverse(c.withReorder(c.withSharedRecordStage(c.withSync(w, c.withFlusher(w, c.newBufferedFormatWriter(w))), seq)))
+}
+
+func (c *Client) newFormatWriter(w io.Writer) logWriter {
+	if c.codec != nil {
+		return &codecWriter{w: w, codec: c.codec}
+	}
+
+	switch c.format {
+	case FormatJSONArray:
+		return c.withEnvelope(&jsonArrayWriter{w: w, omitBrackets: c.omitBrackets})
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w), columns: c.outputColumns(), header: c.emitHeader, strict: c.missingFields == MissingError}
+	case FormatPrettyJSON:
+		return &prettyWriter{w: w}
+	case FormatLengthPrefixed:
+		return &lengthPrefixedWriter{w: w}
+	case FormatTemplate:
+		if c.template == nil {
+			return &errWriter{err: errors.New("Template must be set for FormatTemplate")}
+		}
+		return newTemplateWriter(w, c.template, c.missingFields == MissingError)
+	case FormatFixedWidth:
+		fw, err := c.newFixedWidthWriter(w)
+		if err != nil {
+			return &errWriter{err: err}
+		}
+		return fw
+	}
+
+	return c.withProjection(c.withEnvelope(&ndjsonWriter{w: w}))
+}
+
+// checkFormat checks that the options a format needs are set, so that a
+// misconfigured client fails before any request rather than at its first log.
+// The format is unused, and not checked, when there is a RecordCodec.
+func (c *Client) checkFormat(format OutputFormat) error {
+	if c.codec != nil {
+		return nil
+	}
+
+	switch format {
+	case FormatTemplate:
+		if c.template == nil {
+			return errors.New("Template must be set for FormatTemplate")
+		}
+	case FormatFixedWidth:
+		return checkFixedWidth(c.fixedWidth)
+	}
+
+	return nil
+}
+
+// withNDJSON returns a clone of the client that writes logs as plain NDJSON,
+// whatever its output format: without a codec or an envelope, so that
+// whatever reads the lines back gets the logs themselves.
+func (c *Client) withNDJSON() *Client {
+	nc := c.clone()
+	nc.format = FormatNDJSON
+	nc.codec = nil
+	nc.envelope = nil
+	return nc
+}
+
+// withEnvelope wraps lw in an envelopeWriter if Options.EnvelopeFunc is set.
+func (c *Client) withEnvelope(lw logWriter) logWriter {
+	if c.envelope == nil {
+		return lw
+	}
+
+	return &envelopeWriter{forwarder: forwarder{next: lw}, fn: c.envelope}
+}
+
+type ndjsonWriter struct {
+	w io.Writer
+}
+
+func (n *ndjsonWriter) writeLog(line []byte) error {
+	if _, err := n.w.Write(line); err != nil {
+		return err
+	}
+	_, err := n.w.Write([]byte("\n"))
+	return err
+}
+
+func (n *ndjsonWriter) close() error { return nil }
+
+// errWriter is a logWriter that fails every write, for reporting an invalid
+// output configuration when the first log is written.
+type errWriter struct {
+	err error
+}
+
+func (e *errWriter) writeLog([]byte) error { return e.err }
+func (e *errWriter) close() error          { return nil }
+
+// prettyWriter indents each log as it is read, so that output is still
+// streamed log-by-log.
+type prettyWriter struct {
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+func (p *prettyWriter) writeLog(line []byte) error {
+	p.buf.Reset()
+	if err := json.Indent(&p.buf, line, "", "  "); err != nil {
+		return errors.Wrap(err, "failed to indent log")
+	}
+	p.buf.WriteString("\n\n")
+
+	_, err := p.w.Write(p.buf.Bytes())
+	return err
+}
+
+func (p *prettyWriter) close() error { return nil }
+
+// jsonArrayWriter writes logs as a JSON array, one element per line. With
+// omitBrackets set, the elements are written comma-separated without the
+// enclosing brackets, so that fragments can be concatenated (joined with a
+// comma) into a larger array.
+type jsonArrayWriter struct {
+	w            io.Writer
+	omitBrackets bool
+	n            int
+}
+
+func (j *jsonArrayWriter) writeLog(line []byte) error {
+	sep := ",\n"
+	if j.n == 0 {
+		sep = ""
+		if !j.omitBrackets {
+			sep = "[\n"
+		}
+	}
+	j.n++
+
+	if _, err := io.WriteString(j.w, sep); err != nil {
+		return err
+	}
+	_, err := j.w.Write(line)
+	return err
+}
+
+func (j *jsonArrayWriter) close() error {
+	switch {
+	case j.omitBrackets && j.n > 0:
+		_, err := io.WriteString(j.w, "\n")
+		return err
+	case j.omitBrackets:
+		return nil
+	case j.n == 0:
+		_, err := io.WriteString(j.w, "[]\n")
+		return err
+	}
+
+	_, err := io.WriteString(j.w, "\n]\n")
+	return err
+}
+
+// csvWriter writes logs as CSV rows. The header row is only written when
+// header is set, and is written before the first row (or on close, if no logs
+// were written but the columns are known).
+type csvWriter struct {
+	w       *csv.Writer
+	columns []string
+	header  bool
+	row     []string
+	// Fail on a log that lacks a column, rather than write an empty cell.
+	strict bool
+}
+
+func (c *csvWriter) writeLog(line []byte) error {
+	rec, err := decodeLog(line)
+	if err != nil {
+		return err
+	}
+
+	return c.writeRecord(rec)
+}
+
+func (c *csvWriter) writeRecord(rec LogRecord) error {
+	if c.columns == nil {
+		c.columns = sortedKeys(rec)
+	}
+
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	c.row = c.row[:0]
+	for _, col := range c.columns {
+		v, ok := rec[col]
+		if !ok && c.strict {
+			return missingField(col)
+		}
+		c.row = append(c.row, csvValue(v))
+	}
+
+	return c.w.Write(c.row)
+}
+
+func (c *csvWriter) writeHeader() error {
+	if !c.header || c.columns == nil {
+		return nil
+	}
+	c.header = false
+
+	return c.w.Write(c.columns)
+}
+
+func (c *csvWriter) close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+
+	return c.w.Error()
+}
+
+// csvValue formats a decoded JSON value as a CSV cell. Strings and numbers are
+// written as-is, null as an empty cell, and objects and arrays as JSON.
+func csvValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+
+	return string(b)
+}
+
+func sortedKeys(rec LogRecord) []string {
+	keys := make([]string, 0, len(rec))
+	for k := range rec {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
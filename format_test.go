@@ -0,0 +1,53 @@
+package logshare
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewChecksFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{
+			name: "template without a template",
+			opts: Options{Format: FormatTemplate},
+			want: "Template must be set",
+		},
+		{
+			name: "template",
+			opts: Options{Format: FormatTemplate, Template: "{{.RayID}}"},
+		},
+		{
+			name: "fixed width without fields",
+			opts: Options{Format: FormatFixedWidth},
+			want: "FixedWidthFields must be set",
+		},
+		{
+			name: "fixed width without a width",
+			opts: Options{Format: FormatFixedWidth, FixedWidthFields: []FixedWidthField{{Name: "RayID"}}},
+			want: `field "RayID" must have a positive width`,
+		},
+		{
+			name: "fixed width",
+			opts: Options{Format: FormatFixedWidth, FixedWidthFields: []FixedWidthField{{Name: "RayID", Width: 16}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New("token", "", "", &tt.opts)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package logshare
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// lengthPrefixedWriter writes each log as a frame: its length as a 4-byte
+// big-endian integer, followed by the log's JSON.
+type lengthPrefixedWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+func (l *lengthPrefixedWriter) writeLog(line []byte) error {
+	l.buf = append(l.buf[:0], 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(l.buf, uint32(len(line)))
+	l.buf = append(l.buf, line...)
+
+	_, err := l.w.Write(l.buf)
+	return err
+}
+
+func (l *lengthPrefixedWriter) close() error { return nil }
+
+// ErrFrameTooLarge is returned by a FrameReader for a frame longer than its
+// limit.
+var ErrFrameTooLarge = errors.New("frame exceeds the maximum size")
+
+// FrameReader reads the logs written with FormatLengthPrefixed, one frame at
+// a time.
+type FrameReader struct {
+	r   io.Reader
+	max int
+	buf []byte
+}
+
+// NewFrameReader returns a FrameReader that reads frames from r of up to
+// maxFrameBytes each (DefaultMaxLineBytes if it is zero or less), so that a
+// corrupt length can't exhaust memory.
+func NewFrameReader(r io.Reader, maxFrameBytes int) *FrameReader {
+	if maxFrameBytes <= 0 {
+		maxFrameBytes = DefaultMaxLineBytes
+	}
+
+	return &FrameReader{r: r, max: maxFrameBytes}
+}
+
+// Next returns the next log. The slice is only valid until the next call. At
+// the end of the stream, Next returns io.EOF; a stream that ends part way
+// through a frame returns io.ErrUnexpectedEOF.
+func (f *FrameReader) Next() ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(f.r, size[:]); err != nil {
+		return nil, err
+	}
+
+	n := binary.BigEndian.Uint32(size[:])
+	if uint64(n) > uint64(f.max) {
+		return nil, errors.Wrapf(ErrFrameTooLarge, "%d bytes", n)
+	}
+
+	if cap(f.buf) < int(n) {
+		f.buf = make([]byte, n)
+	}
+	f.buf = f.buf[:n]
+
+	if _, err := io.ReadFull(f.r, f.buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return f.buf, nil
+}
@@ -0,0 +1,94 @@
+package logshare
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// syncer is implemented by destinations that can flush writes to stable
+// storage, such as *os.File.
+type syncer interface {
+	Sync() error
+}
+
+// withSync wraps lw in a syncWriter if Options.FsyncOnComplete is set and w
+// can be synced.
+func (c *Client) withSync(w io.Writer, lw logWriter) logWriter {
+	s, ok := w.(syncer)
+	if !c.fsync || !ok {
+		return lw
+	}
+
+	return &syncWriter{forwarder: forwarder{next: lw}, w: w, s: s, every: c.fsyncEvery}
+}
+
+// syncWriter is a logWriter that syncs its destination once the logs have
+// been written, and optionally every so many logs.
+type syncWriter struct {
+	forwarder
+	w     io.Writer
+	s     syncer
+	every int
+	n     int
+}
+
+func (s *syncWriter) writeLog(line []byte) error {
+	if err := s.next.writeLog(line); err != nil {
+		return err
+	}
+
+	return s.wrote()
+}
+
+func (s *syncWriter) writeRecord(rec LogRecord) error {
+	if err := writeRecordTo(s.next, rec); err != nil {
+		return err
+	}
+
+	return s.wrote()
+}
+
+func (s *syncWriter) wrote() error {
+	s.n++
+	if s.every > 0 && s.n%s.every == 0 {
+		return s.sync()
+	}
+
+	return nil
+}
+
+func (s *syncWriter) sync() error {
+	if err := flushBuffer(s.next); err != nil {
+		return err
+	}
+	if err := flushWriter(s.w); err != nil {
+		return err
+	}
+
+	return errors.Wrap(s.s.Sync(), "failed to sync destination")
+}
+
+func (s *syncWriter) close() error {
+	if err := s.next.close(); err != nil {
+		return err
+	}
+
+	return s.sync()
+}
+
+func (s *syncWriter) markEmpty(line string) error {
+	if err := markEmpty(s.next, line); err != nil {
+		return err
+	}
+
+	return s.sync()
+}
+
+func (s *syncWriter) writeSummary(line []byte) error {
+	if err := writeSummaryTo(s.next, line); err != nil {
+		return err
+	}
+
+	return s.sync()
+}
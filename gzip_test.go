@@ -0,0 +1,240 @@
+package logshare
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// testLogs returns n newline delimited logs.
+func testLogs(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "{\"RayID\":\"%016x\"}\n", i)
+	}
+
+	return b.String()
+}
+
+func gzipped(t *testing.T, s string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestAcceptGzipNegotiation(t *testing.T) {
+	logs := testLogs(3)
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		err      string
+	}{
+		{name: "gzip", encoding: "gzip", body: gzipped(t, logs)},
+		{name: "Accept-Encoding ignored", body: []byte(logs)},
+		{name: "identity", encoding: "identity", body: []byte(logs)},
+		{name: "unsupported encoding", encoding: "br", body: []byte("not brotli"), err: `unsupported response Content-Encoding "br"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Accept-Encoding"); got != "gzip" {
+					t.Errorf("Accept-Encoding = %q, want gzip", got)
+				}
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+				}
+				w.Write(tt.body)
+			})
+
+			var out bytes.Buffer
+			c, srv := newTestClient(t, handler, &Options{AcceptGzip: true, Dest: &out})
+			defer srv.Close()
+
+			start := time.Now().Add(-time.Hour).Unix()
+			meta, err := c.GetFromTimestamp(testZoneID, start, start+60, 0)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if out.String() != logs {
+				t.Errorf("wrote %q, want %q", out.String(), logs)
+			}
+			if meta.Count != 3 {
+				t.Errorf("Count = %d, want 3", meta.Count)
+			}
+			if meta.ContentEncoding != tt.encoding {
+				t.Errorf("ContentEncoding = %q, want %q", meta.ContentEncoding, tt.encoding)
+			}
+		})
+	}
+}
+
+func TestGzipTruncated(t *testing.T) {
+	whole := gzipped(t, testLogs(3))
+	long := fmt.Sprintf("{\"RayID\":\"%016x\",\"Pad\":\"%s\"}\n", 3, strings.Repeat("x", 4096))
+	next := gzipped(t, long)
+
+	tests := []struct {
+		name  string
+		body  []byte
+		count int
+	}{
+		// The second member is cut before its only log's newline, so just
+		// the first member's logs are complete.
+		{name: "mid-member", body: append(whole[:len(whole):len(whole)], next[:len(next)/2]...), count: 3},
+		// Every log is decompressed, but the trailer is missing.
+		{name: "missing trailer", body: whole[:len(whole)-4], count: 3},
+		{name: "header only", body: append(whole[:len(whole):len(whole)], next[:4]...), count: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Encoding", "gzip")
+				w.Write(tt.body)
+			})
+
+			var out bytes.Buffer
+			c, srv := newTestClient(t, handler, &Options{AcceptGzip: true, Dest: &out})
+			defer srv.Close()
+
+			start := time.Now().Add(-time.Hour).Unix()
+			meta, err := c.GetFromTimestamp(testZoneID, start, start+60, 0)
+			if errors.Cause(err) != ErrResponseTruncated {
+				t.Fatalf("err = %v, want ErrResponseTruncated", err)
+			}
+			if want := fmt.Sprintf("after %d logs", tt.count); !strings.Contains(err.Error(), want) {
+				t.Errorf("err = %v, want it to contain %q", err, want)
+			}
+
+			if meta == nil {
+				t.Fatal("no Meta returned with the error")
+			}
+			if meta.Count != tt.count {
+				t.Errorf("Count = %d, want %d", meta.Count, tt.count)
+			}
+			if meta.Bytes != int64(out.Len()) {
+				t.Errorf("Bytes = %d, want %d", meta.Bytes, out.Len())
+			}
+			if meta.ContentEncoding != "gzip" {
+				t.Errorf("ContentEncoding = %q, want gzip", meta.ContentEncoding)
+			}
+			if out.String() != testLogs(tt.count) {
+				t.Errorf("wrote %q, want the %d complete logs", out.String(), tt.count)
+			}
+		})
+	}
+}
+
+func TestRotatingGzipWriterTruncatedPull(t *testing.T) {
+	whole := gzipped(t, testLogs(5))
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(whole[:len(whole)-4])
+	})
+
+	dir, err := ioutil.TempDir("", "logshare")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rw := NewRotatingGzipWriter(dir, "logs", &RotateOptions{BlockRecords: 2})
+	c, srv := newTestClient(t, handler, &Options{AcceptGzip: true, Dest: rw})
+	defer srv.Close()
+
+	start := time.Now().Add(-time.Hour).Unix()
+	_, err = c.GetFromTimestamp(testZoneID, start, start+60, 0)
+	if errors.Cause(err) != ErrResponseTruncated {
+		t.Fatalf("err = %v, want ErrResponseTruncated", err)
+	}
+
+	if err := rw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(rw.Files) != 1 {
+		t.Fatalf("wrote %d files, want 1", len(rw.Files))
+	}
+
+	f, err := os.Open(rw.Files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("rotated file does not decompress: %v", err)
+	}
+	if string(b) != testLogs(5) {
+		t.Errorf("decompressed %q, want %q", b, testLogs(5))
+	}
+}
+
+func TestRotatingGzipWriterWriteError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logshare")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rw := NewRotatingGzipWriter(dir, "logs", &RotateOptions{MaxRecords: 2})
+	if _, err := rw.Write([]byte(`{"RayID":"a"}` + "\n" + `{"RayID":`)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first file is open, but the next can't be created.
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	p := []byte(`"b"}` + "\n" + `{"RayID":"c"}` + "\n")
+	n, err := rw.Write(p)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if want := len(`"b"}` + "\n"); n != want {
+		t.Errorf("wrote %d bytes, want %d", n, want)
+	}
+	if len(rw.partial) != 0 {
+		t.Errorf("kept %q of the failed log", rw.partial)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	p = []byte(`{"RayID":"d"}` + "\n")
+	if n, err := rw.Write(p); err != nil || n != len(p) {
+		t.Errorf("Write = %d, %v after recovering, want %d, nil", n, err, len(p))
+	}
+}
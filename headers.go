@@ -0,0 +1,60 @@
+package logshare
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// builtinHeaders are the headers the client sets itself, which are always
+// allowed by Options.AllowedHeaders.
+var builtinHeaders = []string{
+	"Authorization",
+	"X-Auth-Key",
+	"X-Auth-Email",
+	"Accept",
+	"Accept-Encoding",
+	"Content-Type",
+	"User-Agent",
+}
+
+// newHeaderAllowList returns the set of headers the client may send, in
+// canonical form, or nil if any header may be sent.
+func newHeaderAllowList(allowed []string) map[string]bool {
+	if allowed == nil {
+		return nil
+	}
+
+	set := make(map[string]bool, len(allowed)+len(builtinHeaders))
+	for _, h := range builtinHeaders {
+		set[h] = true
+	}
+	for _, h := range allowed {
+		set[http.CanonicalHeaderKey(h)] = true
+	}
+
+	return set
+}
+
+// checkHeaders returns an error naming the headers of h that are not in the
+// client's allow-list, if it has one.
+func (c *Client) checkHeaders(h http.Header) error {
+	if c.allowedHeaders == nil {
+		return nil
+	}
+
+	var denied []string
+	for name := range h {
+		if !c.allowedHeaders[http.CanonicalHeaderKey(name)] {
+			denied = append(denied, name)
+		}
+	}
+
+	if len(denied) > 0 {
+		sort.Strings(denied)
+		return errors.Errorf("headers not in AllowedHeaders: %v", denied)
+	}
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package logshare
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// testZoneID is a zone ID that is used as is, without looking up a zone name.
+const testZoneID = "023e105f4ecef8ad9ca31a8372d0c353"
+
+// newTestClient returns a client for the API served by handler. The server
+// must be closed.
+func newTestClient(t *testing.T, handler http.Handler, opts *Options) (*Client, *httptest.Server) {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	c, err := New("token", "", "", opts)
+	if err != nil {
+		srv.Close()
+		t.Fatal(err)
+	}
+	c.endpoint = srv.URL
+
+	return c, srv
+}
@@ -0,0 +1,66 @@
+package logshare
+
+import (
+	"bytes"
+	"strings"
+)
+
+// HostField is the log field Options.HostFilter matches against.
+const HostField = "ClientRequestHost"
+
+// hostFilter drops the logs of other hostnames than host. The Logpull API
+// can't filter by host, so every log of the zone is still fetched; the filter
+// saves decoding, transforming and writing the rest. Logs are matched on the
+// raw line where possible, before they are decoded.
+type hostFilter struct {
+	host    string
+	matched int
+}
+
+func newHostFilter(host string) *hostFilter {
+	if host == "" {
+		return nil
+	}
+
+	return &hostFilter{host: host}
+}
+
+func (h *hostFilter) matchLine(line []byte) bool {
+	if h == nil {
+		return true
+	}
+
+	v := lineValue(line, HostField)
+	if len(v) < 2 || v[0] != '"' {
+		return false
+	}
+
+	return h.match(bytes.EqualFold(v[1:len(v)-1], []byte(h.host)))
+}
+
+func (h *hostFilter) matchRecord(rec LogRecord) bool {
+	if h == nil {
+		return true
+	}
+
+	v, _ := rec[HostField].(string)
+
+	return h.match(strings.EqualFold(v, h.host))
+}
+
+func (h *hostFilter) match(ok bool) bool {
+	if ok {
+		h.matched++
+	}
+
+	return ok
+}
+
+func (h *hostFilter) report(meta *Meta) {
+	if h == nil {
+		return
+	}
+
+	meta.Matched += h.matched
+	h.matched = 0
+}
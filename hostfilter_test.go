@@ -0,0 +1,51 @@
+package logshare
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHostFilterSummaries(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 1; i <= 10; i++ {
+			host := "a.example.com"
+			if i%2 == 0 {
+				host = "B.example.com"
+			}
+			fmt.Fprintf(w, `{"ClientRequestHost":%q,"ClientIP":"192.0.2.%d","OriginResponseTime":%d}`+"\n", host, i, i)
+		}
+	})
+	c, srv := newTestClient(t, handler, &Options{HostFilter: "b.example.com"})
+	defer srv.Close()
+
+	start := time.Now().Add(-time.Hour).Unix()
+	end := start + 60
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	meta, err := c.ExtractField(ctx, testZoneID, start, end, 0, "ClientIP", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "192.0.2.2\n192.0.2.4\n192.0.2.6\n192.0.2.8\n192.0.2.10\n"; buf.String() != want {
+		t.Errorf("ExtractField wrote %q, want %q", buf.String(), want)
+	}
+	if meta.Count != 10 || meta.Matched != 5 {
+		t.Errorf("ExtractField: Count = %d, Matched = %d; want 10, 5", meta.Count, meta.Matched)
+	}
+
+	ps, meta, err := c.Percentiles(ctx, testZoneID, start, end, 0, "OriginResponseTime", []float64{0, 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps[0] != 2 || ps[100] != 10 {
+		t.Errorf("Percentiles = %v, want min 2 and max 10", ps)
+	}
+	if meta.SampleSize != 5 {
+		t.Errorf("SampleSize = %d, want 5", meta.SampleSize)
+	}
+}
@@ -0,0 +1,179 @@
+package logshare
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultInitialLookback is how far back Incremental starts from when there
+// is no checkpoint and no Options.InitialLookback is set.
+const DefaultInitialLookback = time.Hour
+
+// Checkpointer stores the cursor of an incremental pull: the time up to which
+// a zone's logs have been written.
+type Checkpointer interface {
+	// LoadCheckpoint returns the cursor for the zone, or false if there is
+	// none yet.
+	LoadCheckpoint(zoneID string) (time.Time, bool, error)
+	// SaveCheckpoint replaces the cursor for the zone. It should do so
+	// atomically, so that a crash never leaves a partial cursor behind.
+	SaveCheckpoint(zoneID string, cursor time.Time) error
+}
+
+// Incremental writes the logs for a zone from its last checkpoint up to the
+// latest available logs (see Options.AvailabilityLag), then advances the
+// checkpoint. On the first run, with no checkpoint, it starts from
+// Options.InitialLookback before the latest available logs.
+//
+// The logs are fetched in windows of at most maxWindow (no limit if zero).
+// After each window is written and w flushed (if it has a Flush method), the
+// checkpoint is advanced to the end of the window, so a failed run resumes
+// from the last complete window and never skips logs; the logs of a window
+// that failed part way may be written twice. This makes Incremental safe to
+// call repeatedly, such as from cron, provided runs for a zone do not
+// overlap.
+//
+// With Options.HoldBackIncomplete set, only whole windows of maxWindow are
+// fetched: a trailing window that would be cut short by the latest available
+// logs is left for a later run, once all of it is available, rather than
+// fetched now and again later. The checkpoint stays at its start, and
+// Meta.HeldBack reports how long it is.
+//
+// Options.MaxWallClock bounds the run as a whole. A window cut short by it is
+// not checkpointed: the run stops there, setting Meta.Partial, and the next
+// run fetches the window again. Likewise, a window that reaches the response
+// cap (see Options.ResponseCapPolicy) fails the run without being
+// checkpointed, unless CapPaginate fetches the rest of it.
+//
+// Each window is written to w as a request of its own, so FormatJSONArray
+// requires OmitBrackets, and FormatCSV can't be used with EmitHeader.
+func (c *Client) Incremental(ctx context.Context, zoneID string, checkpointer Checkpointer, maxWindow time.Duration, w io.Writer) (*Meta, error) {
+	if c.codec == nil {
+		switch {
+		case c.format == FormatJSONArray && !c.omitBrackets:
+			return nil, errors.New("Incremental writes each window as a request of its own, so FormatJSONArray requires OmitBrackets")
+		case c.format == FormatCSV && c.emitHeader:
+			return nil, errors.New("Incremental writes each window as a request of its own, so FormatCSV can't be used with EmitHeader")
+		}
+	}
+
+	ctx, cancel := c.withWallClock(ctx)
+	defer cancel()
+
+	end := c.availableEnd()
+
+	cursor, ok, err := checkpointer.LoadCheckpoint(zoneID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load checkpoint")
+	}
+	if !ok {
+		cursor = end.Add(-c.initialLookback)
+	}
+
+	if maxWindow <= 0 {
+		maxWindow = end.Sub(cursor)
+	}
+
+	total := &Meta{}
+	for cursor.Before(end) {
+		next := cursor.Add(maxWindow)
+		if next.After(end) {
+			if c.holdBack {
+				total.HeldBack = end.Sub(cursor)
+				break
+			}
+			next = end
+		}
+
+		meta, err := c.getFromTimestamp(ctx, zoneID, cursor.Unix(), next.Unix(), 0, c.newLogWriter(w))
+		total.add(meta)
+		total.Polls++
+		if meta != nil && meta.StatusCode == http.StatusNoContent {
+			err = nil
+		}
+		if err != nil {
+			return total, err
+		}
+
+		if err := flushWriter(w); err != nil {
+			return total, errors.Wrap(err, "failed to flush logs")
+		}
+
+		if meta.Partial {
+			break
+		}
+
+		if meta.CapReached {
+			return total, errors.Errorf("the window from %s to %s reached the response cap, so the rest of its logs were not fetched: use CapPaginate or a smaller maxWindow", cursor.UTC().Format(time.RFC3339), next.UTC().Format(time.RFC3339))
+		}
+
+		if err := checkpointer.SaveCheckpoint(zoneID, next); err != nil {
+			return total, errors.Wrap(err, "failed to save checkpoint")
+		}
+		cursor = next
+	}
+
+	return total, nil
+}
+
+// FileCheckpointer is a Checkpointer that keeps each zone's cursor in a file
+// named after the zone in Dir.
+type FileCheckpointer struct {
+	Dir string
+}
+
+// LoadCheckpoint implements Checkpointer.
+func (f *FileCheckpointer) LoadCheckpoint(zoneID string) (time.Time, bool, error) {
+	b, err := ioutil.ReadFile(f.path(zoneID))
+	if os.IsNotExist(err) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(string(b)))
+	if err != nil {
+		return time.Time{}, false, errors.Wrapf(err, "invalid checkpoint in %s", f.path(zoneID))
+	}
+
+	return t, true, nil
+}
+
+// SaveCheckpoint implements Checkpointer, writing the cursor to a temporary
+// file and renaming it into place.
+func (f *FileCheckpointer) SaveCheckpoint(zoneID string, cursor time.Time) error {
+	tmp, err := ioutil.TempFile(f.Dir, zoneID+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(cursor.UTC().Format(time.RFC3339) + "\n"); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), f.path(zoneID))
+}
+
+func (f *FileCheckpointer) path(zoneID string) string {
+	return filepath.Join(f.Dir, zoneID+".checkpoint")
+}
@@ -0,0 +1,83 @@
+package logshare
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// cappedServer serves three logs for a window, and the last of them and one
+// more when asked for the logs from a RayID, as a window cut short by a
+// response cap of three would.
+func cappedServer() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("start_id") != "" {
+			fmt.Fprintf(w, "{\"RayID\":\"%016x\"}\n{\"RayID\":\"%016x\"}\n", 2, 3)
+			return
+		}
+		w.Write([]byte(testLogs(3)))
+	})
+}
+
+func TestIncrementalCapReached(t *testing.T) {
+	c, srv := newTestClient(t, cappedServer(), &Options{ResponseCap: 3})
+	defer srv.Close()
+
+	cursor := c.availableEnd().Add(-time.Minute)
+	cp := memCheckpointer{testZoneID: cursor}
+
+	_, err := c.Incremental(context.Background(), testZoneID, cp, 0, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "reached the response cap") {
+		t.Errorf("err = %v, want the response cap to fail the run", err)
+	}
+	if !cp[testZoneID].Equal(cursor) {
+		t.Errorf("checkpoint moved to %v, want %v", cp[testZoneID], cursor)
+	}
+}
+
+func TestIncrementalCapPaginate(t *testing.T) {
+	c, srv := newTestClient(t, cappedServer(), &Options{ResponseCap: 3, ResponseCapPolicy: CapPaginate})
+	defer srv.Close()
+
+	cursor := c.availableEnd().Add(-time.Minute)
+	cp := memCheckpointer{testZoneID: cursor}
+
+	var out bytes.Buffer
+	meta, err := c.Incremental(context.Background(), testZoneID, cp, 0, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if meta.Count != 4 || out.String() != testLogs(4) {
+		t.Errorf("Count = %d, wrote %q", meta.Count, out.String())
+	}
+	if !cp[testZoneID].After(cursor) {
+		t.Error("checkpoint not advanced")
+	}
+}
+
+func TestIncrementalFramedFormats(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "JSON array", opts: Options{Format: FormatJSONArray}},
+		{name: "CSV header", opts: Options{Format: FormatCSV, EmitHeader: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, srv := newTestClient(t, cappedServer(), &tt.opts)
+			defer srv.Close()
+
+			_, err := c.Incremental(context.Background(), testZoneID, memCheckpointer{}, time.Minute, &bytes.Buffer{})
+			if err == nil || !strings.Contains(err.Error(), "request of its own") {
+				t.Errorf("err = %v, want the format to be rejected", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package logshare
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultMaxIndexedRecords is the most logs GetIndexedByRayID keeps when no
+// Options.MaxIndexedRecords is set.
+const DefaultMaxIndexedRecords = 100000
+
+// GetIndexedByRayID fetches the logs between the start and end timestamps (up
+// to 'count' logs) into a map of RayID to log, for looking logs up while
+// correlating them with other data. RayID must be in Fields, if Fields are
+// set, or it fails before making a request. At most Options.MaxIndexedRecords
+// logs are kept: once the map is full, later logs are read but dropped, and
+// Meta.IndexFull is set.
+func (c *Client) GetIndexedByRayID(ctx context.Context, zoneID string, start int64, end int64, count int) (map[string]LogRecord, *Meta, error) {
+	if len(c.fields) > 0 && !hasField(c.fields, "RayID") {
+		return nil, nil, errors.New("RayID must be in Fields to index logs by RayID")
+	}
+
+	ix := &rayIndex{records: make(map[string]LogRecord), max: c.maxIndexed}
+	meta, err := c.getFromTimestamp(ctx, zoneID, start, end, count, c.withRecordStage(ix))
+	if meta != nil && meta.StatusCode == http.StatusNoContent {
+		err = nil
+	}
+	if meta != nil {
+		meta.IndexFull = ix.full
+	}
+
+	return ix.records, meta, err
+}
+
+// rayIndex is a logWriter that keeps up to max logs, by RayID.
+type rayIndex struct {
+	records map[string]LogRecord
+	max     int
+	full    bool
+}
+
+func (r *rayIndex) writeLog(line []byte) error {
+	rec, err := decodeLog(line)
+	if err != nil {
+		return err
+	}
+
+	return r.writeRecord(rec)
+}
+
+func (r *rayIndex) writeRecord(rec LogRecord) error {
+	ray, _ := rec["RayID"].(string)
+	if ray == "" {
+		return nil
+	}
+
+	if _, ok := r.records[ray]; !ok && len(r.records) >= r.max {
+		r.full = true
+		return nil
+	}
+	r.records[ray] = rec
+
+	return nil
+}
+
+func (r *rayIndex) close() error { return nil }
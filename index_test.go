@@ -0,0 +1,28 @@
+package logshare
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetIndexedByRayIDNeedsRayID(t *testing.T) {
+	requests := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(testLogs(1)))
+	})
+	c, ts := newTestClient(t, handler, &Options{Fields: []string{TimestampField}})
+	defer ts.Close()
+
+	start := time.Now().Add(-time.Hour).Unix()
+	_, _, err := c.GetIndexedByRayID(context.Background(), testZoneID, start, start+60, 0)
+	if err == nil || !strings.Contains(err.Error(), "RayID must be in Fields") {
+		t.Errorf("err = %v, want RayID to be required", err)
+	}
+	if requests != 0 {
+		t.Errorf("made %d requests, want none", requests)
+	}
+}
@@ -0,0 +1,59 @@
+package logshare
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// InspectFields fetches a sample of up to sampleCount logs between the start
+// and end timestamps, and returns the number of logs in which each field had a
+// value. Fields that are null or an empty string are not counted, so a field
+// that is requested but never populated is reported with a count of zero.
+func (c *Client) InspectFields(ctx context.Context, zoneID string, start int64, end int64, sampleCount int) (map[string]int, error) {
+	if sampleCount <= 0 {
+		return nil, errors.New("sampleCount must be greater than zero")
+	}
+
+	u, err := c.timestampURL(ctx, zoneID, start, end, sampleCount)
+	if err != nil {
+		return nil, err
+	}
+
+	fc := &fieldCounter{counts: make(map[string]int)}
+	for _, f := range c.fields {
+		fc.counts[f] = 0
+	}
+
+	if _, err := c.request(ctx, u, fc); err != nil {
+		return nil, err
+	}
+
+	return fc.counts, nil
+}
+
+// fieldCounter is a logWriter that counts the populated fields of each log.
+type fieldCounter struct {
+	counts map[string]int
+}
+
+func (f *fieldCounter) writeLog(line []byte) error {
+	rec, err := decodeLog(line)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range rec {
+		if v == nil || v == "" {
+			if _, ok := f.counts[k]; !ok {
+				f.counts[k] = 0
+			}
+			continue
+		}
+		f.counts[k]++
+	}
+
+	return nil
+}
+
+func (f *fieldCounter) close() error { return nil }
@@ -0,0 +1,83 @@
+//go:build go1.23
+// +build go1.23
+
+package logshare
+
+import (
+	"context"
+	"iter"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// errStopIteration ends a pull when the caller of RecordsSeq stops ranging.
+var errStopIteration = errors.New("iteration stopped")
+
+// RecordsSeq fetches the logs between the start and end timestamps (up to
+// 'count' logs) like GetFromTimestamp, but yields them decoded rather than
+// writing them to the destination:
+//
+//	for rec, err := range client.RecordsSeq(ctx, zoneID, start, end, 0) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// Logs are read and yielded one at a time, as they are received, after any
+// record-level options; the output format does not apply. A failed pull
+// yields its error last, with a nil LogRecord; an empty window yields
+// nothing. Breaking out of the loop ends the request, releasing its
+// connection.
+func (c *Client) RecordsSeq(ctx context.Context, zoneID string, start int64, end int64, count int) iter.Seq2[LogRecord, error] {
+	return func(yield func(LogRecord, error) bool) {
+		yw := &yieldWriter{ctx: ctx, yield: yield}
+		meta, err := c.getFromTimestamp(ctx, zoneID, start, end, count, c.withRecordStage(yw))
+		if meta != nil && meta.StatusCode == http.StatusNoContent {
+			err = nil
+		}
+		if yw.stopped || err == nil {
+			return
+		}
+
+		yield(nil, err)
+	}
+}
+
+// yieldWriter is a logWriter that passes each log to the body of a range
+// loop.
+type yieldWriter struct {
+	ctx     context.Context
+	yield   func(LogRecord, error) bool
+	stopped bool
+}
+
+func (y *yieldWriter) writeLog(line []byte) error {
+	rec, err := decodeLog(line)
+	if err != nil {
+		return err
+	}
+
+	return y.writeRecord(rec)
+}
+
+func (y *yieldWriter) writeRecord(rec LogRecord) error {
+	if y.stopped {
+		return errStopIteration
+	}
+	if err := y.ctx.Err(); err != nil {
+		return err
+	}
+
+	if !y.yield(rec, nil) {
+		y.stopped = true
+		return errStopIteration
+	}
+
+	return nil
+}
+
+func (y *yieldWriter) close() error {
+	return nil
+}
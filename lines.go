@@ -0,0 +1,62 @@
+package logshare
+
+import (
+	"context"
+	"net/http"
+)
+
+// LinesChannel fetches logs between the start and end timestamps (up to
+// 'count' logs) and delivers each log line, without its newline, on the
+// returned channel, which holds up to bufSize lines. It is cheaper than
+// StreamRecords for consumers that route logs without reading them: lines are
+// not decoded, unless a record-level option such as AddIngestField requires
+// it. Reading stops while the channel is full, applying backpressure to the
+// response. The error channel receives at most one error, once the line
+// channel is closed; an empty window is not an error.
+//
+// Each line is a fresh slice that the receiver owns and may retain or modify.
+// Cancelling the context ends the request and closes both channels; lines
+// already buffered may still be received.
+func (c *Client) LinesChannel(ctx context.Context, zoneID string, start int64, end int64, count int, bufSize int) (<-chan []byte, <-chan error) {
+	if bufSize < 0 {
+		bufSize = 0
+	}
+
+	lines := make(chan []byte, bufSize)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+		defer close(lines)
+
+		lw := &lineChannelWriter{ctx: ctx, ch: lines}
+		meta, err := c.getFromTimestamp(ctx, zoneID, start, end, count, c.withRecordStage(lw))
+		if meta != nil && meta.StatusCode == http.StatusNoContent {
+			err = nil
+		}
+		if err != nil {
+			errc <- err
+		}
+	}()
+
+	return lines, errc
+}
+
+// lineChannelWriter is a logWriter that sends copies of raw log lines on a
+// channel.
+type lineChannelWriter struct {
+	ctx context.Context
+	ch  chan<- []byte
+}
+
+func (l *lineChannelWriter) writeLog(line []byte) error {
+	// The scanner reuses its buffer, so each line must be copied.
+	select {
+	case l.ch <- append([]byte(nil), line...):
+		return nil
+	case <-l.ctx.Done():
+		return l.ctx.Err()
+	}
+}
+
+func (l *lineChannelWriter) close() error { return nil }
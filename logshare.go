@@ -2,15 +2,24 @@ package logshare
 
 import (
 	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
+	"unicode/utf8"
+
 	"github.com/pkg/errors"
 )
 
@@ -29,37 +38,499 @@ const (
 // Client holds the current API credentials & HTTP client configuration. Client
 // should not be modified concurrently.
 type Client struct {
-	endpoint        string
-	apiToken	string
-	apiKey          string
-	apiEmail        string
-	byReceived      bool
-	sample          float64
-	timestampFormat string
-	fields          []string
-	httpClient      *http.Client
-	dest            io.Writer
-	headers         http.Header
+	endpoint         string
+	apiToken         string
+	apiKey           string
+	apiEmail         string
+	byReceived       bool
+	skipRayIDTime    bool
+	sample           float64
+	sampleSeed       int64
+	timestampFormat  string
+	fields           []string
+	httpClient       *http.Client
+	dest             io.Writer
+	headers          http.Header
+	invalidUTF8      UTF8Policy
+	trace            bool
+	format           OutputFormat
+	emitHeader       bool
+	omitBrackets     bool
+	sem              chan struct{}
+	slowWriter       SlowWriterPolicy
+	slowBuffer       int
+	verify           VerifyFunc
+	codec            RecordCodec
+	maxCount         int
+	ingestField      string
+	availabilityLag  time.Duration
+	fixedWidth       []FixedWidthField
+	fieldSeparator   string
+	flatten          bool
+	flattenArrays    ArrayPolicy
+	flattenDepth     int
+	duplicates       DuplicatePolicy
+	emitEmptyMarker  bool
+	emptyMarkerLine  string
+	fieldsEncoding   FieldsEncoding
+	acceptGzip       bool
+	sequence         bool
+	sequenceField    string
+	sequenceBase     int64
+	sequenceSkipped  bool
+	reverse          bool
+	maxResponseBytes int64
+	responseCap      int
+	capPolicy        CapPolicy
+	template         *template.Template
+	missingFields    MissingFieldPolicy
+	zoneResolver     ZoneResolver
+	zones            *zoneCache
+	sendRequestID    bool
+	requestIDHeader  string
+	requestIDFunc    func() string
+	breaker          *circuitBreaker
+	initialLookback  time.Duration
+	redactions       map[string]Redaction
+	redactionSalt    string
+	retention        time.Duration
+	metrics          MetricsRecorder
+	auth             AuthType
+	recordProgress   RecordProgressFunc
+	progressInterval int
+	requestMethod    string
+	emitSummary      bool
+	summaryField     string
+	detectSkew       bool
+	skew             *clockSkew
+	fsync            bool
+	fsyncEvery       int
+
+	retryOnEmpty       int
+	retryOnEmptyDelay  time.Duration
+	retryOnEmptyWindow time.Duration
+	renames            map[string]string
+	schemaCache        *SchemaCache
+	maxIndexed         int
+	envelope           EnvelopeFunc
+	redirects          RedirectPolicy
+	paramNames         ParamNames
+	maxWallClock       time.Duration
+	receivedOrder      ReceivedOrder
+	reorderBuffer      int
+	maxLineBytes       int
+	deadLetter         *deadLetter
+	endpointCaps       map[string]EndpointCapabilities
+	project            []string
+	flushEvery         int
+	dedupKey           string
+	allowedHeaders     map[string]bool
+	customTransport    bool
+	holdBack           bool
+	bufferLines        int
+	bufferBytes        int
+	bufferInterval     time.Duration
+	hostFilter         string
+	spill              bool
+	spillDir           string
+	connRetries        int
+	connRetryBase      time.Duration
+	requestedSample    float64
 }
 
 // Options for configuring log retrieval requests.
 type Options struct {
 	// Provide a custom HTTP client. Defaults to a barebones *http.Client.
 	HTTPClient *http.Client
+	// Provide a custom transport, used when HTTPClient is not set.
+	Transport http.RoundTripper
+	// The TCP keep-alive period and the time an idle connection is kept open
+	// for reuse, used when neither HTTPClient nor Transport is set. Default
+	// to DefaultKeepAlive and DefaultIdleConnTimeout; a negative KeepAlive
+	// disables keep-alives. A client's connections are reused across pulls,
+	// so reuse the client between scheduled runs to benefit.
+	KeepAlive       time.Duration
+	IdleConnTimeout time.Duration
 	// Provide custom HTTP request headers.
 	Headers http.Header
+	// Restrict the headers the client sends to these, besides those it sets
+	// itself (Authorization, X-Auth-Key, X-Auth-Email, Accept,
+	// Accept-Encoding, Content-Type and User-Agent). New fails if Headers,
+	// or the RequestIDHeader under SendRequestID, are not allowed, and each
+	// request is checked before it is sent. Nil allows any header.
+	AllowedHeaders []string
 	// Destination to stream logs to.
 	Dest io.Writer
-	// Fetch logs by the processing/received timestamp
+	// Fetch logs by the processing/received timestamp. Logs are then
+	// returned in the order they were processed, which is not the order of
+	// their EdgeStartTimestamp; see ReceivedOrder.
 	ByReceived bool
+	// What to do about the order of logs fetched ByReceived. Defaults to
+	// ReceivedAsIs. Measuring or reordering logs requires EdgeStartTimestamp
+	// in Fields, if Fields are set.
+	ReceivedOrder ReceivedOrder
+	// Don't check that the end timestamp of GetFromRayID is after the time
+	// of the ray ID, as read by RayIDTime. The check only applies when that
+	// time is plausible, but the ray ID layout is not documented, so it may
+	// reject a valid request.
+	SkipRayIDTimeCheck bool
+	// The number of logs held to reorder them under ReceivedReorder, which
+	// bounds both memory use and how far out of order a log can be
+	// corrected. Defaults to DefaultReorderBuffer.
+	ReorderBuffer int
+	// The longest log line read, in bytes. A longer log fails the pull.
+	// Defaults to DefaultMaxLineBytes.
+	MaxLineBytes int
+	// Where to write logs that can't be decoded, or that a record-level
+	// option fails on (such as MissingError or DuplicateError), rather than
+	// failing the pull. Each is written as a line of JSON with the error and
+	// the log: {"error":"...","log":"..."}. The log is as returned by the
+	// API, or as far as it was transformed if it was not read from the API
+	// directly. Meta.DeadLettered counts them.
+	DeadLetterWriter io.Writer
+	// Overrides DefaultEndpointCapabilities for the named endpoints, for Log
+	// Share products whose endpoints take different parameters.
+	EndpointCapabilities map[string]EndpointCapabilities
+	// The fields to write, in order, for FormatNDJSON and FormatCSV, out of
+	// those requested: logs can be requested broadly (e.g. to cache them)
+	// and written narrowly. Fields are named as written, after RenameFields,
+	// and must be in Fields (or added by the client, as with Sequence), if
+	// Fields are set. Under FormatNDJSON, each log is then re-encoded with
+	// its fields in this order.
+	ProjectFields []string
+	// How many logs are written between flushes when Dest is an
+	// http.Flusher, such as an http.ResponseWriter (see ServeLogs). Defaults
+	// to DefaultFlushEvery.
+	FlushEvery int
+	// Buffer output, flushing it to Dest once WriteBufferLines logs or
+	// WriteBufferBytes bytes are buffered, or WriteBufferInterval after the
+	// oldest buffered log, whichever comes first, and once the logs have
+	// been written. Batching writes pays off when Dest is a network
+	// connection or a pipe, while the interval bounds the latency of a
+	// quiet stream. Setting any of them enables buffering, and the rest
+	// default to DefaultWriteBufferLines, DefaultWriteBufferBytes and
+	// DefaultWriteBufferInterval; by default, each log is written to Dest as
+	// it is read. The buffer is also flushed before each FsyncEvery sync and
+	// FlushEvery flush, so that those cover every log written.
+	WriteBufferLines    int
+	WriteBufferBytes    int
+	WriteBufferInterval time.Duration
+	// The field that uniquely identifies a log, for dropping the logs that
+	// Follow reads twice when resuming after a partial poll. Defaults to
+	// DefaultDedupKeyField. Pagination always continues from, and
+	// deduplicates by, RayID, which the API pages by.
+	DedupKeyField string
+	// Leave the trailing, incomplete window of an Incremental run for a
+	// later run, rather than fetch part of it now (see Incremental).
+	HoldBackIncomplete bool
+	// Only write the logs of this hostname, matched case-insensitively
+	// against ClientRequestHost, which must be in Fields, if Fields are set.
+	// The API has no such filter, so it is applied by the client as logs
+	// are read: every log in the window is still fetched (and counted in
+	// Meta.Count, and against any count limit), and only matching logs are
+	// written (and counted in Meta.Matched).
+	HostFilter string
 	// Which timestamp format to use: one of "unix", "unixnano", "rfc3339"
 	TimestampFormat string
-	// Whether to only retrieve a sample of logs (0.001 to 1)
+	// Whether to only retrieve a sample of logs (MinSample to NoSampling).
+	// The rate is rounded to a step of MinSample, and clamped to that range;
+	// Meta.SampleRate reports the rate used, and Meta.RequestedSample the
+	// rate asked for. A count caps the logs returned after sampling, not
+	// the logs sampled from: a count of 100 at a rate of 0.1 returns up to
+	// 100 logs, from up to around 1000 in the window.
 	Sample float64
+	// Sample logs on the client, reproducibly, rather than in the API, which
+	// samples randomly and has no seed parameter. With a non-zero SampleSeed,
+	// every log is requested and a log is kept if a hash of the seed and its
+	// RayID falls within Sample, so pulling the same window with the same
+	// seed yields the same logs. RayID must be in Fields, if Fields are set.
+	// As with sampling by the API, a count caps the logs kept: the window is
+	// requested without a count, and the rest of the logs are dropped once
+	// count are kept, which fails on endpoints that require a count. The
+	// seed is reported in Meta.SampleSeed.
+	SampleSeed int64
 	// The fields to return in the log responses
 	Fields []string
+	// How to handle invalid UTF-8 in the streamed logs. Defaults to
+	// UTF8Passthrough, which does not inspect the bytes at all.
+	InvalidUTF8 UTF8Policy
+	// Record DNS, connect, TLS and time-to-first-byte timings for each
+	// request in Meta. Off by default.
+	Trace bool
+	// The format to write logs in. Defaults to FormatNDJSON.
+	Format OutputFormat
+	// Write a header row in FormatCSV. Leave unset when appending to output
+	// that already has a header.
+	EmitHeader bool
+	// Omit the enclosing brackets in FormatJSONArray, so that the output can
+	// be concatenated with other fragments of the same array.
+	//
+	// The header and brackets are written once per request, so each
+	// destination written to by a request is self-contained unless these
+	// options say otherwise.
+	OmitBrackets bool
+	// The maximum number of requests the client has in flight at once, across
+	// all goroutines. Requests over the limit wait for a slot (or for their
+	// context to be cancelled). Zero means no limit.
+	MaxConcurrentRequests int
+	// What to do when the destination can't keep up with the API. Defaults to
+	// Block.
+	SlowWriterPolicy SlowWriterPolicy
+	// The number of logs buffered under DropOldest. Defaults to
+	// DefaultSlowWriterBuffer.
+	SlowWriterBuffer int
+	// Called with the Meta of each request that streamed successfully. A
+	// non-nil error fails the request, so that callers don't advance a cursor
+	// past logs that don't pass their checks.
+	VerifyFunc VerifyFunc
+	// Re-encode each log with a custom codec, in place of Format. Defaults to
+	// nil, which writes logs in Format.
+	RecordCodec RecordCodec
+	// The most logs the API returns per request. Larger counts are fetched in
+	// several requests, each continuing from the RayID of the last log
+	// received (which requires RayID in Fields). Zero means no limit.
+	MaxCount int
+	// Add a field with this name to every log, holding the time of the pull
+	// in RFC3339 format. The time is the same for every log in a pull. This
+	// changes the output schema, and requires decoding and re-encoding each
+	// log, which is considerably slower than writing logs as-is.
+	AddIngestField string
+	// How far behind the current time logs are available from the API, used
+	// by WindowLast and Follow. Defaults to DefaultAvailabilityLag.
+	AvailabilityLag time.Duration
+	// The columns of FormatFixedWidth output, and the separator written
+	// between them (none by default). New checks that there are columns,
+	// each with a positive width.
+	FixedWidthFields []FixedWidthField
+	FieldSeparator   string
+	// Flatten nested objects into dotted keys (e.g. "RequestHeaders.Host"),
+	// for flat formats such as CSV. Every distinct nested key becomes its own
+	// column, so deeply nested or free-form objects can produce a very large
+	// number of columns: cap the nesting with FlattenMaxDepth, beyond which
+	// objects are kept as JSON strings. With FlattenNested set, CSV columns
+	// are taken from the first log rather than Fields.
+	FlattenNested   bool
+	FlattenArrays   ArrayPolicy
+	FlattenMaxDepth int
+	// What to do when AddIngestField or FlattenNested adds a field that the
+	// log already has. Defaults to DuplicateError.
+	DuplicateFields DuplicatePolicy
+	// When a request returns no logs, write a marker so that downstream
+	// systems can tell an empty window from a job that never ran. The marker
+	// is EmptyMarkerLine (DefaultEmptyMarkerLine if unset), or the result of
+	// MarkEmpty if the destination implements EmptyMarker. Only applies to
+	// FormatNDJSON: empty CSV and JSON array output is already distinct.
+	EmitEmptyMarker bool
+	EmptyMarkerLine string
+	// How to encode Fields in the query string. Defaults to FieldsComma.
+	FieldsEncoding FieldsEncoding
+	// Request a gzip compressed response. The response is decompressed only
+	// if the server actually compressed it.
+	AcceptGzip bool
+	// Number each log with a monotonically increasing sequence number,
+	// written to SequenceField (DefaultSequenceField if unset), which is the
+	// leading column of CSV output when Fields are set. Numbers start from
+	// SequenceBase for each pull: pass the next unused number to resume.
+	// With SequenceCountSkipped, logs dropped by other options still use up
+	// a number, so numbers map to the logs the API returned; otherwise the
+	// output is numbered without gaps.
+	Sequence             bool
+	SequenceField        string
+	SequenceBase         int64
+	SequenceCountSkipped bool
+	// Write logs newest first by EdgeStartTimestamp, which must be in Fields
+	// if Fields are set. The whole response is held in memory (decoded, at
+	// several times its size on the wire) until the pull completes, up to
+	// MaxResponseBytes of log data (DefaultMaxResponseBytes if unset), beyond
+	// which the pull fails with ErrResponseTooLarge. Backfill chunks are
+	// each reversed on their own. Reverse cannot be used with Follow.
+	Reverse          bool
+	MaxResponseBytes int64
+	// Under Reverse, spill the logs beyond MaxResponseBytes to a temporary
+	// file in SpillDir (os.TempDir if unset) rather than fail, and read them
+	// back from it once the pull completes. Reversing a large window then
+	// costs disk space and I/O instead of memory; only an index of the
+	// spilled logs is held in memory. The file is removed when the pull
+	// completes or fails, and Meta.SpilledBytes reports its size.
+	SpillToDisk bool
+	SpillDir    string
+	// The most logs the API returns to a request without a count. Defaults
+	// to DefaultResponseCap; a negative value assumes there is no cap.
+	// ResponseCapPolicy decides what happens when a response reaches it.
+	ResponseCap       int
+	ResponseCapPolicy CapPolicy
+	// The text/template used by FormatTemplate. It is parsed by New, which
+	// fails if FormatTemplate is used without one.
+	Template string
+	// How to write logs that lack a requested field. Defaults to
+	// MissingDefault, which leaves it to each format (see
+	// MissingFieldPolicy).
+	MissingFields MissingFieldPolicy
+	// Every method that takes a zone ID also accepts a zone name, which is
+	// resolved to an ID with ZoneResolver. Defaults to a lookup with the
+	// Cloudflare API, using the client's credentials. Names are resolved
+	// once and cached for the lifetime of the client.
+	ZoneResolver ZoneResolver
+	// Send a request ID with every request, in RequestIDHeader
+	// (DefaultRequestIDHeader if unset), to correlate requests with the
+	// logs of gateways or Cloudflare support. IDs are generated by
+	// RequestIDFunc, or are random UUIDs if it is nil. The ID is recorded
+	// in Meta.RequestID, alongside the CF-Ray of the response.
+	SendRequestID   bool
+	RequestIDHeader string
+	RequestIDFunc   func() string
+	// Stop making requests after CircuitThreshold consecutive failures
+	// (transport errors, rate limiting and server errors), failing them
+	// with ErrCircuitOpen for CircuitCooldown (DefaultCircuitCooldown if
+	// unset). A single probe request is then let through, which closes the
+	// circuit if it succeeds. The breaker is shared by every request the
+	// client makes; Meta.CircuitState reports its state. Disabled by
+	// default.
+	CircuitThreshold int
+	CircuitCooldown  time.Duration
+	// How far back Incremental starts when a zone has no checkpoint.
+	// Defaults to DefaultInitialLookback.
+	InitialLookback time.Duration
+	// Redact fields, by name, before logs are written in any format. Logs
+	// are decoded and re-encoded to do so. Use a secret RedactionSalt with
+	// RedactHash, and keep it the same across pulls for values to be
+	// pseudonymized consistently. Meta.Redacted counts the logs redacted
+	// per field.
+	Redactions    map[string]Redaction
+	RedactionSalt string
+	// How long the API retains logs for. Pulls that start earlier than
+	// this before now fail with ErrBeyondRetention rather than return no
+	// logs. Defaults to DefaultRetentionWindow; a negative value disables
+	// the check.
+	RetentionWindow time.Duration
+	// Receive metrics for every request, retry and backfill chunk. Defaults
+	// to NopMetrics.
+	Metrics MetricsRecorder
+	// The auth schemes to use, in order of preference, when credentials
+	// for more than one are given: the first with credentials is used, and
+	// New fails if none of them have any. Defaults to AuthAPIToken, then
+	// AuthAPIKey. Meta.AuthScheme reports the scheme used.
+	AuthPreference []AuthType
+	// Called every RecordProgressInterval logs (defaults to
+	// DefaultRecordProgressInterval) with the progress of the request, and
+	// once more with the total when the request ends. Logs are decoded to
+	// do so. It is called from the goroutine streaming
+	// the response, so must be safe for concurrent use with
+	// ConcurrentBackfill and similar, and should return quickly.
+	RecordProgress         RecordProgressFunc
+	RecordProgressInterval int
+	// The HTTP method for requests: GET (the default) or POST. With POST,
+	// parameters are sent as a form-encoded body rather than in the query
+	// string, which avoids proxies that mangle long URLs and URL length
+	// limits with many Fields. Only the endpoints that fetch logs by
+	// timestamp or ray ID range (logs/received and logs/requests) accept
+	// POST, as described by EndpointCapabilities: requests to other
+	// endpoints, such as FetchFieldNames and GetSingleByRayID, fail.
+	RequestMethod string
+	// Write a summary line after the logs of each request, with the number
+	// of logs and bytes, the start and end of the window, the duration of
+	// the request and the first and last RayID. The line is a JSON object
+	// with SummaryField (DefaultSummaryField if unset) set to true, for
+	// consumers to detect and strip it. Only written with FormatNDJSON;
+	// pulls made of several requests (backfill chunks, Follow polls) write
+	// a summary for each, but a pull paged by MaxCount or CapPaginate is
+	// summarized once, after its last page.
+	EmitSummary  bool
+	SummaryField string
+	// Measure the offset of the local clock from the API's, from the Date
+	// header of the first response, and correct for it wherever the current
+	// time is used: the end of Follow polls, WindowLast and GetLast,
+	// Incremental and the RetentionWindow check. The skew is known to
+	// within a second, and is reported in Meta.ClockSkew. Until the first
+	// response, the local clock is used as-is.
+	DetectClockSkew bool
+	// Sync the destination to stable storage (flushing it first, if it has
+	// a Flush method) once the logs of each request have been written, and
+	// every FsyncEvery logs if set, when it has a Sync method like
+	// *os.File. A failed sync fails the request, so that a pull never
+	// reports success for logs that could still be lost.
+	FsyncOnComplete bool
+	FsyncEvery      int
+	// Retry a request that returns no logs up to RetryOnEmpty times, after
+	// RetryOnEmptyDelay (DefaultRetryOnEmptyDelay if unset), as logs may
+	// not have propagated yet. Only windows that end within
+	// RetryOnEmptyWindow (DefaultRetryOnEmptyWindow if unset) of the latest
+	// available logs (see AvailabilityLag) are retried, so that windows
+	// that are genuinely empty are not. Meta.EmptyRetries counts the
+	// retries. Disabled by default.
+	RetryOnEmpty       int
+	RetryOnEmptyDelay  time.Duration
+	RetryOnEmptyWindow time.Duration
+	// Retry a request that fails before a connection is established, such
+	// as on a DNS, dial or TLS handshake error, up to ConnRetries times,
+	// waiting ConnRetryDelay (DefaultConnRetryDelay if unset) and doubling
+	// the wait after each retry, up to a minute. Nothing has been sent, so
+	// this is safe for any request; errors once connected are left to the
+	// caller, as are certificates the client rejects. Meta.ConnRetries
+	// counts the retries. Disabled by default.
+	ConnRetries    int
+	ConnRetryDelay time.Duration
+	// Rename fields in the output, from their API name to a new one, in
+	// every format (including the CSV header). Logs are decoded and
+	// re-encoded to do so. Redactions and MissingFields refer to fields by
+	// their API names; FormatTemplate templates and FixedWidthFields by
+	// their new names. New checks that no two fields are renamed alike and,
+	// if Fields are set, that every renamed field is in them. Otherwise, with
+	// a SchemaCache, each pull checks that they are available in the zone.
+	RenameFields map[string]string
+	// Check Fields against the available fields of each zone before every
+	// pull, using (and filling) the cache. Defaults to nil, which doesn't
+	// check Fields.
+	SchemaCache *SchemaCache
+	// The most logs GetIndexedByRayID keeps in memory. Defaults to
+	// DefaultMaxIndexedRecords.
+	MaxIndexedRecords int
+	// Wraps each log in an envelope before it is written, for FormatNDJSON
+	// and FormatJSONArray. Defaults to writing logs as-is. Every log is then
+	// decoded and re-encoded, which is several times slower than passing the
+	// API's JSON through.
+	EnvelopeFunc EnvelopeFunc
+	// How to handle HTTP redirects. Defaults to RedirectError. Ignored if
+	// HTTPClient has a CheckRedirect of its own.
+	RedirectPolicy RedirectPolicy
+	// The names of the start and end query parameters. Defaults to
+	// ParamNamesStartEnd.
+	ParamNames ParamNames
+	// The longest a call may take, including all of its pages, retries,
+	// Incremental windows or ConcurrentBackfill chunks; each Follow poll is
+	// bounded on its own. Once it is reached, the call stops after the last
+	// complete log and succeeds with the logs read so far, setting
+	// Meta.Partial, rather than failing as a context deadline would. Zero
+	// means no limit.
+	MaxWallClock time.Duration
 }
 
+// VerifyFunc checks the result of a completed request.
+type VerifyFunc func(meta *Meta) error
+
+// UTF8Policy controls how byte sequences that are not valid UTF-8 are handled
+// when streaming logs.
+//
+// Cloudflare logs may contain arbitrary bytes in string fields such as
+// ClientRequestUserAgent or ClientRequestURI. FormatNDJSON and FormatJSONArray
+// write the response through as-is, so without a policy those bytes reach the
+// destination untouched. FormatCSV decodes each log, which replaces invalid
+// sequences with U+FFFD (without counting them) regardless of the policy.
+type UTF8Policy int
+
+const (
+	// UTF8Passthrough writes logs without validating them.
+	UTF8Passthrough UTF8Policy = iota
+	// UTF8Report writes logs unchanged, but counts the logs containing
+	// invalid UTF-8 in Meta.InvalidUTF8.
+	UTF8Report
+	// UTF8Replace replaces each invalid sequence with U+FFFD and counts the
+	// replacements in Meta.UTF8Replacements.
+	UTF8Replace
+	// UTF8Error stops streaming at the first log containing invalid UTF-8.
+	UTF8Error
+)
+
 // Meta contains data about the API response: the number of logs returned,
 // the duration of the request, the HTTP status code and the constructed URL.
 type Meta struct {
@@ -67,6 +538,215 @@ type Meta struct {
 	Duration   int64
 	StatusCode int
 	URL        string
+	// The number of logs containing invalid UTF-8, and the number of invalid
+	// sequences that were replaced. Only populated when Options.InvalidUTF8
+	// is set.
+	InvalidUTF8      int
+	UTF8Replacements int
+	// Connection phase timings, only populated when Options.Trace is set.
+	// Phases that did not happen (e.g. DNS and TLS on a reused connection)
+	// are left at zero.
+	DNSDuration     time.Duration
+	ConnectDuration time.Duration
+	TLSDuration     time.Duration
+	TimeToFirstByte time.Duration
+	ReusedConn      bool
+	// The number of bytes of log data streamed, including newlines.
+	Bytes int64
+	// The number of requests made, and why they stopped (see Follow).
+	Polls      int
+	StopReason string
+	// The number of batches delivered to a Sink, and the number of retries
+	// needed to deliver them.
+	Batches int
+	Retries int
+	// The number of lines written after a LineTransform (see PullAndForward).
+	Transformed int
+	// The number of chunks fetched by a backfill, and the retries left in its
+	// RetryBudget (-1 when unlimited).
+	Chunks               int
+	RetryBudgetRemaining int
+	// The number of logs dropped under the DropOldest SlowWriterPolicy. These
+	// are included in Count.
+	Dropped int
+	// The number of pages fetched beyond MaxCount or the ResponseCap, and
+	// the number of duplicate logs dropped at page boundaries.
+	Pages      int
+	Duplicates int
+	// The number of values truncated to fit a FormatFixedWidth column.
+	Truncated int
+	// The chunks of a backfill that were fetched successfully, and those
+	// skipped because they had been fetched before.
+	FetchedWindows []TimeRange
+	SkippedWindows []TimeRange
+	// The number of logs each zone contributed to MergeZones, and the zones
+	// dropped from the merge for stalling.
+	ZoneCounts   map[string]int
+	DroppedZones []string
+	// The ray IDs passed to GetByRayIDs that did and did not have a log.
+	FoundRayIDs   []string
+	MissingRayIDs []string
+	// Whether the request returned no logs.
+	Empty bool
+	// The number of logs written to each file by PartitionedWrite.
+	Partitions map[string]int
+	// The number of logs redacted, per field (see Options.Redactions).
+	Redacted map[string]int
+	// The number of values written by ExtractField, and the repeated values
+	// skipped by ExtractUniqueField.
+	Extracted    int
+	Deduplicated int
+	// The RayIDs of the first and last logs, only populated when
+	// Options.EmitSummary is set.
+	FirstRayID string
+	LastRayID  string
+	// Whether GetIndexedByRayID dropped logs for reaching
+	// Options.MaxIndexedRecords.
+	IndexFull bool
+	// The number of times an empty response was retried (see
+	// Options.RetryOnEmpty).
+	EmptyRetries int
+	// The number of retries after connection errors (see
+	// Options.ConnRetries).
+	ConnRetries int
+	// The fields added to the zone since a baseline (see PullNewFields).
+	NewFields []string
+	// How far the API's clock is ahead of the local clock, when
+	// Options.DetectClockSkew is set.
+	ClockSkew time.Duration
+	// The auth scheme the request was made with.
+	AuthScheme AuthType
+	// The state of the circuit breaker after the request, if
+	// Options.CircuitThreshold is set (see CircuitClosed).
+	CircuitState string
+	// The ID sent with the request (see Options.SendRequestID), and the
+	// CF-Ray of the response.
+	RequestID string
+	CFRay     string
+	// Whether a request without a count returned Options.ResponseCap logs,
+	// meaning there are likely more logs in the window (see CapPolicy).
+	CapReached bool
+	// The Content-Encoding of the response, if any.
+	ContentEncoding string
+	// The margin of error of EstimateBytes, in bytes, at 95% confidence.
+	EstimateMargin int64
+	// The seed logs were sampled with on the client (see Options.SampleSeed),
+	// or zero if any sampling was done by the API.
+	SampleSeed int64
+	// A summary of the logs, from GetDigestFromTimestamp.
+	Digest *PullDigest
+	// Whether the call was cut short by Options.MaxWallClock.
+	Partial bool
+	// The rate logs were sampled at, or NoSampling (see Options.Sample).
+	SampleRate float64
+	// The Options.Sample and count asked for, before the rate was rounded
+	// and clamped to SampleRate, and before a count was applied to the
+	// sample (see Options.SampleSeed). RequestedCount is set by the pulls
+	// that take a count, and zero for none.
+	RequestedSample float64
+	RequestedCount  int
+	// How far the logs arrived out of EdgeStartTimestamp order: the longest
+	// a log's timestamp was behind that of an earlier log. Only measured
+	// under ReceivedMeasure and ReceivedReorder.
+	MaxReorderSkew time.Duration
+	// The number of logs written to Options.DeadLetterWriter.
+	DeadLettered int
+	// The number of values Percentiles summarized.
+	SampleSize int
+	// The earliest and latest EdgeStartTimestamp of the logs read, which may
+	// fall short of the requested window. Only set when the logs have
+	// EdgeStartTimestamp, as they do if Fields are unset or include it.
+	MinTimestamp time.Time
+	MaxTimestamp time.Time
+	// The bytes written to each output of PullToOutputs, by name.
+	OutputBytes map[string]int64
+	// The number of logs folded by Reduce.
+	Reduced int
+	// How much of the available logs Incremental left for a later run,
+	// under Options.HoldBackIncomplete.
+	HeldBack time.Duration
+	// How much of the logs since the checkpoint FollowResumable skipped,
+	// being older than FollowOptions.MaxLookback.
+	LookbackSkipped time.Duration
+	// The number of logs that matched Options.HostFilter, out of Count.
+	Matched int
+	// The bytes of log data spilled to disk under Options.SpillToDisk.
+	SpilledBytes int64
+	// The number of logs of each status class written by
+	// StatusClassPartitionedWrite, such as "2xx" or "unknown".
+	StatusClasses map[string]int
+}
+
+// add accumulates the counters of o into m, for summarizing several requests.
+// The StatusCode and URL of the most recent request are kept.
+func (m *Meta) add(o *Meta) {
+	if o == nil {
+		return
+	}
+
+	m.Count += o.Count
+	m.Matched += o.Matched
+	m.SpilledBytes += o.SpilledBytes
+	m.Bytes += o.Bytes
+	m.Duration += o.Duration
+	m.InvalidUTF8 += o.InvalidUTF8
+	m.UTF8Replacements += o.UTF8Replacements
+	m.Batches += o.Batches
+	m.Retries += o.Retries
+	m.Transformed += o.Transformed
+	m.Chunks += o.Chunks
+	m.Dropped += o.Dropped
+	m.Pages += o.Pages
+	m.Duplicates += o.Duplicates
+	m.Truncated += o.Truncated
+	for class, n := range o.StatusClasses {
+		if m.StatusClasses == nil {
+			m.StatusClasses = make(map[string]int)
+		}
+		m.StatusClasses[class] += n
+	}
+	for path, n := range o.Partitions {
+		if m.Partitions == nil {
+			m.Partitions = make(map[string]int)
+		}
+		m.Partitions[path] += n
+	}
+	for field, n := range o.Redacted {
+		if m.Redacted == nil {
+			m.Redacted = make(map[string]int)
+		}
+		m.Redacted[field] += n
+	}
+	m.EmptyRetries += o.EmptyRetries
+	m.ConnRetries += o.ConnRetries
+	m.Extracted += o.Extracted
+	m.Deduplicated += o.Deduplicated
+	m.CapReached = m.CapReached || o.CapReached
+	m.Partial = m.Partial || o.Partial
+	m.DeadLettered += o.DeadLettered
+	if !o.MinTimestamp.IsZero() {
+		m.observeTime(o.MinTimestamp)
+		m.observeTime(o.MaxTimestamp)
+	}
+	if o.MaxReorderSkew > m.MaxReorderSkew {
+		m.MaxReorderSkew = o.MaxReorderSkew
+	}
+	m.StatusCode = o.StatusCode
+	m.URL = o.URL
+	m.RequestID = o.RequestID
+	m.CircuitState = o.CircuitState
+	m.AuthScheme = o.AuthScheme
+	m.ClockSkew = o.ClockSkew
+	m.SampleSeed = o.SampleSeed
+	m.SampleRate = o.SampleRate
+	m.RequestedSample = o.RequestedSample
+	if m.FirstRayID == "" {
+		m.FirstRayID = o.FirstRayID
+	}
+	if o.LastRayID != "" {
+		m.LastRayID = o.LastRayID
+	}
+	m.CFRay = o.CFRay
 }
 
 // New creates a new client instance for consuming logs from
@@ -92,11 +772,233 @@ func New(apiToken string, apiKey string, apiEmail string, options *Options) (*Cl
 		dest:       os.Stdout,
 		headers:    make(http.Header),
 		byReceived: byReceived,
+
+		availabilityLag: DefaultAvailabilityLag,
+		responseCap:     DefaultResponseCap,
+		initialLookback: DefaultInitialLookback,
+		maxIndexed:      DefaultMaxIndexedRecords,
+		dedupKey:        DefaultDedupKeyField,
+		retention:       DefaultRetentionWindow,
+		zones:           &zoneCache{},
+		skew:            &clockSkew{},
+		metrics:         NopMetrics{},
 	}
 
 	if options != nil {
+		if options.HTTPClient != nil {
+			client.httpClient = options.HTTPClient
+		} else if hc := newHTTPClient(options); hc != nil {
+			client.httpClient = hc
+		}
+		client.customTransport = options.HTTPClient != nil || options.Transport != nil
+		client.redirects = options.RedirectPolicy
+		if err := options.ParamNames.validate(); err != nil {
+			return nil, err
+		}
+		client.paramNames = options.ParamNames
+		client.maxWallClock = options.MaxWallClock
+
+		if err := options.ReceivedOrder.validate(); err != nil {
+			return nil, err
+		}
+		if options.ReceivedOrder != ReceivedAsIs && options.Fields != nil && !hasField(options.Fields, TimestampField) {
+			return nil, errors.Errorf("%s must be in Fields to measure or reorder logs", TimestampField)
+		}
+		client.receivedOrder = options.ReceivedOrder
+		client.reorderBuffer = options.ReorderBuffer
+		client.maxLineBytes = options.MaxLineBytes
+		client.deadLetter = newDeadLetter(options.DeadLetterWriter)
+		client.endpointCaps = options.EndpointCapabilities
+		client.flushEvery = options.FlushEvery
+		client.holdBack = options.HoldBackIncomplete
+		client.bufferLines = options.WriteBufferLines
+		client.bufferBytes = options.WriteBufferBytes
+		client.bufferInterval = options.WriteBufferInterval
+		if options.HostFilter != "" && options.Fields != nil && !hasField(options.Fields, HostField) {
+			return nil, errors.Errorf("%s must be in Fields to use HostFilter", HostField)
+		}
+		client.hostFilter = options.HostFilter
+		if options.DedupKeyField != "" {
+			if options.Fields != nil && !hasField(options.Fields, options.DedupKeyField) {
+				return nil, errors.Errorf("DedupKeyField %q must be in Fields", options.DedupKeyField)
+			}
+			client.dedupKey = options.DedupKeyField
+		}
+		if client.reorderBuffer <= 0 {
+			client.reorderBuffer = DefaultReorderBuffer
+		}
+
 		client.timestampFormat = options.TimestampFormat
-		client.sample = options.Sample
+		if err := validateSample(options.Sample); err != nil {
+			return nil, err
+		}
+		client.requestedSample = options.Sample
+		if s := effectiveSample(options.Sample); s < NoSampling {
+			client.sample = s
+		}
+		client.sampleSeed = options.SampleSeed
+		if client.seeded() && options.Fields != nil && !hasField(options.Fields, "RayID") {
+			return nil, errors.New("RayID must be in Fields to sample with SampleSeed")
+		}
+		client.invalidUTF8 = options.InvalidUTF8
+		client.trace = options.Trace
+		client.format = options.Format
+		client.emitHeader = options.EmitHeader
+		client.omitBrackets = options.OmitBrackets
+
+		client.slowWriter = options.SlowWriterPolicy
+		client.slowBuffer = options.SlowWriterBuffer
+		client.verify = options.VerifyFunc
+		client.codec = options.RecordCodec
+		client.maxCount = options.MaxCount
+		client.ingestField = options.AddIngestField
+		client.fixedWidth = options.FixedWidthFields
+		client.fieldSeparator = options.FieldSeparator
+		client.flatten = options.FlattenNested
+		client.flattenArrays = options.FlattenArrays
+		client.flattenDepth = options.FlattenMaxDepth
+		client.duplicates = options.DuplicateFields
+		client.emitEmptyMarker = options.EmitEmptyMarker
+		client.fieldsEncoding = options.FieldsEncoding
+		client.acceptGzip = options.AcceptGzip
+		client.sequence = options.Sequence
+		client.sequenceField = options.SequenceField
+		client.sequenceBase = options.SequenceBase
+		client.sequenceSkipped = options.SequenceCountSkipped
+		if client.sequenceField == "" {
+			client.sequenceField = DefaultSequenceField
+		}
+		client.emptyMarkerLine = options.EmptyMarkerLine
+		if client.emptyMarkerLine == "" {
+			client.emptyMarkerLine = DefaultEmptyMarkerLine
+		}
+
+		client.skipRayIDTime = options.SkipRayIDTimeCheck
+		client.reverse = options.Reverse
+		client.maxResponseBytes = options.MaxResponseBytes
+		client.spill = options.SpillToDisk
+		client.spillDir = options.SpillDir
+		if client.maxResponseBytes <= 0 {
+			client.maxResponseBytes = DefaultMaxResponseBytes
+		}
+		if client.reverse && options.Fields != nil && !hasField(options.Fields, TimestampField) {
+			return nil, errors.Errorf("%s must be in Fields to write logs newest first", TimestampField)
+		}
+
+		if options.Template != "" {
+			tmpl, err := compileTemplate(options.Template, options.MissingFields == MissingError)
+			if err != nil {
+				return nil, err
+			}
+			client.template = tmpl
+		}
+		if err := client.checkFormat(client.format); err != nil {
+			return nil, err
+		}
+		client.missingFields = options.MissingFields
+		client.zoneResolver = options.ZoneResolver
+		client.redactions = options.Redactions
+		client.redactionSalt = options.RedactionSalt
+		client.breaker = newCircuitBreaker(options.CircuitThreshold, options.CircuitCooldown)
+		client.sendRequestID = options.SendRequestID
+		client.requestIDFunc = options.RequestIDFunc
+		client.requestIDHeader = options.RequestIDHeader
+		if client.requestIDHeader == "" {
+			client.requestIDHeader = DefaultRequestIDHeader
+		}
+
+		if options.Headers != nil {
+			client.headers = cloneHeader(options.Headers)
+		}
+		client.allowedHeaders = newHeaderAllowList(options.AllowedHeaders)
+		if err := client.checkHeaders(client.headers); err != nil {
+			return nil, err
+		}
+		if client.sendRequestID {
+			if err := client.checkHeaders(http.Header{client.requestIDHeader: nil}); err != nil {
+				return nil, err
+			}
+		}
+
+		client.capPolicy = options.ResponseCapPolicy
+		if options.ResponseCap != 0 {
+			client.responseCap = options.ResponseCap
+		}
+
+		switch options.RequestMethod {
+		case "", http.MethodGet, http.MethodPost:
+			client.requestMethod = options.RequestMethod
+		default:
+			return nil, errors.Errorf("unsupported RequestMethod %q: use GET or POST", options.RequestMethod)
+		}
+
+		if err := validateRenames(options.RenameFields, options.Fields); err != nil {
+			return nil, err
+		}
+		client.renames = options.RenameFields
+		client.schemaCache = options.SchemaCache
+		client.envelope = options.EnvelopeFunc
+		if options.MaxIndexedRecords > 0 {
+			client.maxIndexed = options.MaxIndexedRecords
+		}
+
+		client.retryOnEmpty = options.RetryOnEmpty
+		client.retryOnEmptyDelay = options.RetryOnEmptyDelay
+		if client.retryOnEmptyDelay <= 0 {
+			client.retryOnEmptyDelay = DefaultRetryOnEmptyDelay
+		}
+		client.retryOnEmptyWindow = options.RetryOnEmptyWindow
+		client.connRetries = options.ConnRetries
+		client.connRetryBase = options.ConnRetryDelay
+		if client.connRetryBase <= 0 {
+			client.connRetryBase = DefaultConnRetryDelay
+		}
+		if client.retryOnEmptyWindow <= 0 {
+			client.retryOnEmptyWindow = DefaultRetryOnEmptyWindow
+		}
+
+		client.fsync = options.FsyncOnComplete
+		client.fsyncEvery = options.FsyncEvery
+		client.detectSkew = options.DetectClockSkew
+		client.emitSummary = options.EmitSummary
+		client.summaryField = options.SummaryField
+		if client.summaryField == "" {
+			client.summaryField = DefaultSummaryField
+		}
+
+		client.recordProgress = options.RecordProgress
+		client.progressInterval = options.RecordProgressInterval
+		if client.progressInterval <= 0 {
+			client.progressInterval = DefaultRecordProgressInterval
+		}
+
+		if len(options.AuthPreference) > 0 {
+			auth, err := client.chooseAuth(options.AuthPreference)
+			if err != nil {
+				return nil, err
+			}
+			client.auth = auth
+		}
+
+		if options.Metrics != nil {
+			client.metrics = options.Metrics
+		}
+
+		if options.RetentionWindow != 0 {
+			client.retention = options.RetentionWindow
+		}
+
+		if options.InitialLookback > 0 {
+			client.initialLookback = options.InitialLookback
+		}
+
+		if options.AvailabilityLag > 0 {
+			client.availabilityLag = options.AvailabilityLag
+		}
+
+		if options.MaxConcurrentRequests > 0 {
+			client.sem = make(chan struct{}, options.MaxConcurrentRequests)
+		}
 
 		if options.Dest != nil {
 			client.dest = options.Dest
@@ -105,12 +1007,47 @@ func New(apiToken string, apiKey string, apiEmail string, options *Options) (*Cl
 		if options.Fields != nil {
 			client.fields = options.Fields
 		}
+
+		client.project = options.ProjectFields
+		if err := client.validateProjection(); err != nil {
+			return nil, err
+		}
+	}
+
+	client.httpClient = withRedirectPolicy(client.httpClient, client.redirects)
+
+	if client.auth == "" {
+		auth, err := client.chooseAuth(defaultAuthPreference)
+		if err != nil {
+			return nil, err
+		}
+		client.auth = auth
 	}
 
 	return client, nil
 }
 
-func (c *Client) buildURL(zoneID string, params url.Values) (*url.URL, error) {
+// clone returns a copy of the client for a call that needs other settings,
+// such as other fields or another output format. The copy shares the
+// client's connections and its state across requests: the concurrency limit,
+// circuit breaker, zone cache, clock skew, schema cache, dead-letter writer
+// and metrics. Its maps and slices of configuration (such as headers, fields
+// and redactions) are shared too, so must be replaced rather than modified.
+func (c *Client) clone() *Client {
+	nc := *c
+	return &nc
+}
+
+func (c *Client) buildURL(ctx context.Context, zoneID string, params url.Values) (*url.URL, error) {
+	zoneID, err := c.resolveZone(zoneID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.checkFields(ctx, zoneID); err != nil {
+		return nil, err
+	}
+
 	endpoint := byReceived
 	if !c.byReceived {
 		endpoint = byRequest
@@ -127,11 +1064,15 @@ func (c *Client) buildURL(zoneID string, params url.Values) (*url.URL, error) {
 		return nil, err
 	}
 
-	if c.byReceived && len(c.fields) >= 1 {
-		params.Set("fields", strings.Join(c.fields, ","))
+	if err := c.applyCount(endpoint, params); err != nil {
+		return nil, err
 	}
 
-	if c.sample != 0.0 {
+	if c.byReceived {
+		c.setFields(params)
+	}
+
+	if c.sample != 0.0 && !c.seeded() {
 		params.Set("sample", strconv.FormatFloat(c.sample, 'f', 3, 64))
 	}
 
@@ -143,70 +1084,238 @@ func (c *Client) buildURL(zoneID string, params url.Values) (*url.URL, error) {
 	return u, nil
 }
 
+// FieldsEncoding controls how the fields parameter is encoded in the query
+// string.
+type FieldsEncoding int
+
+const (
+	// FieldsComma sends a single comma-separated parameter, as in
+	// "fields=RayID,ClientIP". This is what the API currently expects, and is
+	// the default.
+	FieldsComma FieldsEncoding = iota
+	// FieldsRepeated sends one parameter per field, as in
+	// "fields=RayID&fields=ClientIP".
+	FieldsRepeated
+)
+
+// setFields adds the requested fields to params, in the client's
+// FieldsEncoding.
+func (c *Client) setFields(params url.Values) {
+	if len(c.fields) == 0 {
+		return
+	}
+
+	if c.fieldsEncoding == FieldsRepeated {
+		params["fields"] = append([]string(nil), c.fields...)
+		return
+	}
+
+	params.Set("fields", strings.Join(c.fields, ","))
+}
+
 // GetFromTimestamp fetches logs between the start and end timestamps provided,
-// (up to 'count' logs).
+// (up to 'count' logs). Counts above Options.MaxCount are fetched in pages.
 func (c *Client) GetFromTimestamp(zoneID string, start int64, end int64, count int) (*Meta, error) {
+	return c.getFromTimestamp(context.Background(), zoneID, start, end, count, c.newLogWriter(c.dest))
+}
+
+// GetFromRayID fetches logs starting at the given ray ID up to the end
+// timestamp, (up to 'count' logs). As with GetFromTimestamp, a count of zero
+// fetches every log in the range, in which case end must be set. An end
+// before the time of the ray ID (see RayIDTime) fails, unless
+// Options.SkipRayIDTimeCheck is set.
+func (c *Client) GetFromRayID(zoneID string, rayID string, end int64, count int) (*Meta, error) {
+	return c.getFromRayID(context.Background(), zoneID, rayID, end, count, c.newLogWriter(c.dest))
+}
+
+func (c *Client) getFromRayID(ctx context.Context, zoneID string, rayID string, end int64, count int, lw logWriter) (*Meta, error) {
+	// Catch an end that precedes the ray, when its time can be determined.
+	if t, err := RayIDTime(rayID); err == nil && !c.skipRayIDTime && end > 0 && unixSeconds(end) <= t.Unix() {
+		return nil, errors.Errorf("end timestamp %d is not after ray ID %s (%s)", end, rayID, t.UTC().Format(time.RFC3339))
+	}
+
+	u, err := c.rayIDURL(ctx, zoneID, rayID, end, count)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.request(ctx, u, lw)
+}
+
+func (c *Client) rayIDURL(ctx context.Context, zoneID string, rayID string, end int64, count int) (*url.URL, error) {
+	if rayID == "" {
+		return nil, errors.New("rayID cannot be empty")
+	}
+
+	if end <= 0 && count <= 0 {
+		return nil, errors.New("an end timestamp is required when count is not set")
+	}
+
 	params := url.Values{}
-	params.Set("start", strconv.FormatInt(start, 10))
+	params.Set("start_id", rayID)
 
 	if end > 0 {
-		params.Set("end", strconv.FormatInt(end, 10))
+		params.Set(c.endParam(), strconv.FormatInt(end, 10))
 	}
 
 	if count > 0 {
 		params.Set("count", strconv.Itoa(count))
 	}
 
-	u, err := c.buildURL(zoneID, params)
-	if err != nil {
+	return c.buildURL(ctx, zoneID, params)
+}
+
+func (c *Client) timestampURL(ctx context.Context, zoneID string, start int64, end int64, count int) (*url.URL, error) {
+	if err := c.checkRetention(start); err != nil {
 		return nil, err
 	}
 
-	return c.request(u)
+	params := url.Values{}
+	params.Set(c.startParam(), strconv.FormatInt(start, 10))
+
+	if end > 0 {
+		params.Set(c.endParam(), strconv.FormatInt(end, 10))
+	}
+
+	if count > 0 {
+		params.Set("count", strconv.Itoa(count))
+	}
+
+	return c.buildURL(ctx, zoneID, params)
 }
 
 // FetchFieldNames fetches the names of the available log fields.
 func (c *Client) FetchFieldNames(zoneID string) (*Meta, error) {
-	u, err := url.Parse(
+	u, err := c.fieldsURL(zoneID)
+	if err != nil {
+		return nil, err
+	}
+	// The field names are a single JSON object, so are always written as-is.
+	return c.request(context.Background(), u, &ndjsonWriter{w: c.dest})
+}
+
+func (c *Client) fieldsURL(zoneID string) (*url.URL, error) {
+	zoneID, err := c.resolveZone(zoneID)
+	if err != nil {
+		return nil, err
+	}
+
+	return url.Parse(
 		fmt.Sprintf(
 			"%s/zones/%s/logs/received/fields",
 			c.endpoint,
 			zoneID,
 		),
 	)
-	if err != nil {
-		return nil, err
-	}
-	return c.request(u)
 }
 
-func (c *Client) request(u *url.URL) (*Meta, error) {
-	req, err := http.NewRequest("GET", u.String(), nil)
+// requestOnce makes a single request for u. If retryEmpty is set, an empty
+// response returns errRetryEmpty without writing anything to lw.
+func (c *Client) requestOnce(ctx context.Context, u *url.URL, lw logWriter, retryEmpty bool) (*Meta, error) {
+	req, err := c.newRequest(u)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create a request object")
 	}
+	req = req.WithContext(ctx)
 
 	// Apply any user-defined headers in a thread-safe manner.
 	req.Header = cloneHeader(c.headers)
-	if (c.apiToken != "") {
+	if c.auth == AuthAPIToken {
 		req.Header.Set("Authorization", "Bearer "+c.apiToken)
 	} else {
 		req.Header.Set("X-Auth-Key", c.apiKey)
 		req.Header.Set("X-Auth-Email", c.apiEmail)
 	}
 	req.Header.Set("Accept", "application/json")
+	if c.acceptGzip {
+		// Setting Accept-Encoding ourselves disables the transport's
+		// transparent decompression, so the response is decoded below.
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+
+	// A request holds its slot until the response has been fully streamed.
+	if c.sem != nil {
+		select {
+		case c.sem <- struct{}{}:
+			defer func() { <-c.sem }()
+		case <-ctx.Done():
+			return nil, errors.Wrap(ctx.Err(), "waiting for a request slot")
+		}
+	}
+
+	meta := &Meta{URL: u.String(), AuthScheme: c.auth, SampleRate: effectiveSample(c.sample), RequestedSample: c.requestedSample}
+	if c.seeded() {
+		meta.SampleSeed = c.sampleSeed
+	}
+	if err := c.checkHeaders(req.Header); err != nil {
+		return meta, err
+	}
+
+	if err := c.breaker.allow(); err != nil {
+		meta.CircuitState = c.breaker.state()
+		return meta, err
+	}
+
+	if id := c.requestID(); id != "" {
+		req.Header.Set(c.requestIDHeader, id)
+		meta.RequestID = id
+	}
+
+	if c.trace {
+		trace, stop := traceTimings(meta)
+		defer stop()
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	}
+
+	var connected bool
+	if c.connRetries > 0 {
+		req = req.WithContext(traceConnected(req.Context(), &connected))
+	}
+
+	began := time.Now()
+	defer func() {
+		c.metrics.IncRequests(meta.StatusCode)
+		c.metrics.ObserveBytes(meta.Bytes)
+		c.metrics.ObserveDuration(time.Since(began))
+	}()
 
 	start := makeTimestamp()
 	resp, err := c.httpClient.Do(req)
+	switch {
+	case err != nil && ctx.Err() != nil:
+		c.breaker.abandon()
+	case err != nil:
+		c.breaker.done(true)
+	default:
+		c.breaker.done(resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500)
+	}
+	meta.CircuitState = c.breaker.state()
+
 	if err != nil {
-		return nil, errors.Wrap(err, "HTTP request failed")
+		// errors.Cause can't see through a *url.Error, so unwrap a refused
+		// redirect to keep ErrRedirect its cause.
+		if ue, ok := err.(*url.Error); ok && errors.Cause(ue.Err) == ErrRedirect {
+			err = ue.Err
+		}
+
+		retry := c.connRetries > 0 && !connected && connRetryable(ctx, err)
+		if meta.RequestID != "" {
+			err = errors.Wrapf(err, "HTTP request %s failed", meta.RequestID)
+		} else {
+			err = errors.Wrap(err, "HTTP request failed")
+		}
+		if retry {
+			return nil, &connError{err: err}
+		}
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	meta := &Meta{
-		StatusCode: resp.StatusCode,
-		Duration:   makeTimestamp() - start,
-		URL:        u.String(),
+	meta.StatusCode = resp.StatusCode
+	meta.Duration = makeTimestamp() - start
+	meta.CFRay = resp.Header.Get("CF-Ray")
+	if c.detectSkew {
+		meta.ClockSkew = c.skew.observe(resp)
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
@@ -217,48 +1326,289 @@ func (c *Client) request(u *url.URL) (*Meta, error) {
 			return meta, errors.Wrapf(err, "HTTP status %d: request failed", resp.StatusCode)
 		}
 
+		if logsNotEnabled(resp.StatusCode, body) {
+			return meta, errors.Wrapf(ErrLogsNotEnabled, "HTTP status %d", resp.StatusCode)
+		}
+
 		return meta, errors.Errorf("HTTP status %d: request failed: %s", resp.StatusCode, body)
 	}
 
 	// Explicitly handle the 204 No Content case.
 	if resp.StatusCode == 204 {
+		if retryEmpty {
+			return meta, errRetryEmpty
+		}
+
+		meta.Empty = true
+		var werr error
+		if c.emitEmptyMarker {
+			werr = errors.Wrap(markEmpty(lw, c.emptyMarkerLine), "failed to mark empty pull")
+		}
+
+		// Finish the output as streamLogs does, so that e.g. a CSV header or
+		// an empty JSON array is written for an empty window too.
+		if cerr := lw.close(); werr == nil && cerr != nil {
+			werr = errors.Wrap(cerr, "failed to write logs")
+		}
+		reportTo(lw, meta)
+		if werr != nil {
+			return meta, werr
+		}
+
 		return meta, errors.Errorf("HTTP status %d: no logs available. Check that Log Share is enabled for your domain or that you are not attempting to retrieve logs too quickly", resp.StatusCode)
 	}
 
+	body, err := decodeBody(resp)
+	if err != nil {
+		return meta, err
+	}
+	defer body.Close()
+	meta.ContentEncoding = resp.Header.Get("Content-Encoding")
+
+	var r io.Reader = body
+	if retryEmpty {
+		var empty bool
+		if r, empty = emptyBody(body); empty {
+			return meta, errRetryEmpty
+		}
+	}
+
+	bindMeta(lw, meta)
+
 	// Stream the logs from the response to the destination writer.
-	meta.Count, err = streamLogs(resp.Body, c.dest)
+	err = c.streamLogs(r, lw, meta)
 	if err != nil {
 		return meta, errors.Wrap(err, "failed to stream logs")
 	}
 
+	if c.capReached(queryCount(u), meta) {
+		meta.CapReached = true
+	}
+
+	if c.emitSummary {
+		if err := c.writeSummary(lw, u, meta, time.Since(began)); err != nil {
+			return meta, err
+		}
+	}
+
+	if c.verify != nil {
+		if err := c.verify(meta); err != nil {
+			return meta, errors.Wrap(err, "verification failed")
+		}
+	}
+
 	return meta, nil
 }
 
-// streamLogs streams newline delimited logs to the provided writer, counting
-// each newline-delimited JSON log without allocating.
+// ErrResponseTruncated is returned when a response ends part way, such as a
+// compressed response that is cut short. The logs read before the failure
+// have been written, and are complete: a log is never written in part.
+var ErrResponseTruncated = errors.New("response was truncated")
+
+// truncated reports whether a read error means the response was cut short.
+func truncated(err error) bool {
+	return err == io.ErrUnexpectedEOF || err == gzip.ErrChecksum || err == gzip.ErrHeader
+}
+
+// decodeBody returns a reader for the decoded response body, based on its
+// Content-Encoding. The server may ignore Accept-Encoding and respond
+// uncompressed, in which case the body is read as-is.
+func decodeBody(resp *http.Response) (io.ReadCloser, error) {
+	switch enc := strings.ToLower(resp.Header.Get("Content-Encoding")); enc {
+	case "", "identity":
+		return ioutil.NopCloser(resp.Body), nil
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read gzip response")
+		}
+		return gz, nil
+	default:
+		return nil, errors.Errorf("unsupported response Content-Encoding %q", enc)
+	}
+}
+
+// streamLogs streams newline delimited logs to the provided logWriter,
+// counting each newline-delimited JSON log.
 //
 // An io.MultiWriter can be created to stream logs to two (or more) different
 // sinks: e.g. stdout and a file simultaneously, or a file and a
 // http.ResponseWriter.
-func streamLogs(r io.Reader, w io.Writer) (int, error) {
-	const MB = 1024 * 1024 * 1024
-	var count = 0
+//
+// A leading UTF-8 byte order mark is never written to the destination.
+func (c *Client) streamLogs(r io.Reader, lw logWriter, meta *Meta) error {
+	if c.slowWriter == DropOldest {
+		lw = newDroppingWriter(lw, c.slowBuffer)
+	}
+
+	err := c.scanLogs(r, lw, meta)
+	if err == nil && meta.Count == 0 {
+		meta.Empty = true
+		if c.emitEmptyMarker {
+			err = errors.Wrap(markEmpty(lw, c.emptyMarkerLine), "failed to mark empty pull")
+		}
+	}
+
+	// Always finish the output so that e.g. buffered CSV rows are flushed,
+	// even if the stream failed part way.
+	if cerr := lw.close(); err == nil && cerr != nil {
+		err = errors.Wrap(cerr, "failed to write logs")
+	}
 
-	scanner := bufio.NewScanner(r)
+	reportTo(lw, meta)
+
+	return err
+}
+
+func (c *Client) scanLogs(r io.Reader, lw logWriter, meta *Meta) error {
+	scanner := NewLogScanner(r, c.maxLineBytes)
 
 	// TODO: Consider a buffer pool to read the track the last log read, for
 	// checkpointing the rayID.
 	for scanner.Scan() {
-		w.Write(scanner.Bytes())
-		w.Write([]byte("\n"))
-		count++
+		line := scanner.Bytes()
+		if meta.Count == 0 {
+			line = bytes.TrimPrefix(line, utf8BOM)
+		}
+
+		line, err := c.checkUTF8(line, meta)
+		if err != nil {
+			return err
+		}
+
+		if err := lw.writeLog(line); err != nil {
+			return errors.Wrap(err, "failed to write log")
+		}
+		meta.Count++
+		meta.Bytes += int64(len(line)) + 1
+
+		if t, ok := lineTime(line); ok {
+			meta.observeTime(t)
+		}
+
+		if c.emitSummary {
+			meta.LastRayID = rayIDOf(line)
+			if meta.Count == 1 {
+				meta.FirstRayID = meta.LastRayID
+			}
+		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return count, errors.Wrap(err, "reading response:")
+		if truncated(err) {
+			return errors.Wrapf(ErrResponseTruncated, "after %d logs: %v", meta.Count, err)
+		}
+		if err == bufio.ErrTooLong {
+			return errors.Wrapf(err, "log %d is longer than MaxLineBytes", meta.Count+1)
+		}
+		return errors.Wrap(err, "reading response:")
+	}
+
+	return nil
+}
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// checkUTF8 applies the client's UTF8Policy to a single log line.
+func (c *Client) checkUTF8(line []byte, meta *Meta) ([]byte, error) {
+	if c.invalidUTF8 == UTF8Passthrough || utf8.Valid(line) {
+		return line, nil
+	}
+
+	meta.InvalidUTF8++
+	switch c.invalidUTF8 {
+	case UTF8Error:
+		return nil, errors.Errorf("log %d contains invalid UTF-8", meta.Count+1)
+	case UTF8Replace:
+		return replaceInvalidUTF8(line, meta), nil
+	}
+
+	return line, nil
+}
+
+// replaceInvalidUTF8 returns a copy of b with each invalid byte sequence
+// replaced by utf8.RuneError.
+func replaceInvalidUTF8(b []byte, meta *Meta) []byte {
+	out := make([]byte, 0, len(b)+8)
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size == 1 {
+			out = append(out, "\uFFFD"...)
+			meta.UTF8Replacements++
+		} else {
+			out = append(out, b[:size]...)
+		}
+		b = b[size:]
+	}
+
+	return out
+}
+
+// traceTimings returns a ClientTrace that records the connection phase
+// timings of a request into meta, and a func that stops the recording, to be
+// called before meta is returned: the dial can outlive a cancelled request.
+//
+// The hooks may run concurrently: the dialer may race connections to several
+// addresses (RFC 6555), each with its own ConnectStart and ConnectDone. Only
+// the first successful connect is recorded, timed from its own start.
+func traceTimings(meta *Meta) (*httptrace.ClientTrace, func()) {
+	var (
+		mu                        sync.Mutex
+		stopped, connected        bool
+		start, dnsStart, tlsStart time.Time
+		connectStarts             = make(map[string]time.Time)
+	)
+
+	// record runs f under the lock, unless recording has stopped.
+	record := func(f func()) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !stopped {
+			f()
+		}
+	}
+
+	stop := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		stopped = true
 	}
 
-	return count, nil
+	return &httptrace.ClientTrace{
+		GetConn: func(string) {
+			record(func() { start = time.Now() })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			record(func() { meta.ReusedConn = info.Reused })
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			record(func() { dnsStart = time.Now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			record(func() { meta.DNSDuration = time.Since(dnsStart) })
+		},
+		ConnectStart: func(network, addr string) {
+			record(func() { connectStarts[network+" "+addr] = time.Now() })
+		},
+		ConnectDone: func(network, addr string, err error) {
+			record(func() {
+				if err != nil || connected {
+					return
+				}
+				connected = true
+				meta.ConnectDuration = time.Since(connectStarts[network+" "+addr])
+			})
+		},
+		TLSHandshakeStart: func() {
+			record(func() { tlsStart = time.Now() })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			record(func() { meta.TLSDuration = time.Since(tlsStart) })
+		},
+		GotFirstResponseByte: func() {
+			record(func() { meta.TimeToFirstByte = time.Since(start) })
+		},
+	}, stop
 }
 
 func makeTimestamp() int64 {
@@ -0,0 +1,202 @@
+package logshare
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Default MergeOptions.
+const (
+	DefaultMergeBufferDepth  = 1000
+	DefaultMergeStallTimeout = 30 * time.Second
+)
+
+// StallPolicy controls what MergeZones does when a zone stops delivering logs.
+type StallPolicy int
+
+const (
+	// MergeWait waits for every zone, so logs are always written in
+	// timestamp order, at the pace of the slowest zone. This is the default.
+	MergeWait StallPolicy = iota
+	// MergeDropStalled drops a zone from the merge once it has delivered
+	// nothing for StallTimeout, and carries on with the remaining zones. The
+	// dropped zone's buffered logs are discarded and counted in Meta.Dropped,
+	// and its later logs are not fetched, so the output is still in order but
+	// incomplete.
+	MergeDropStalled
+)
+
+// MergeOptions configures MergeZones.
+type MergeOptions struct {
+	// The number of decoded logs buffered per zone. This bounds the memory
+	// used by the merge regardless of how far one zone lags behind the rest.
+	// Defaults to DefaultMergeBufferDepth.
+	BufferDepth int
+	// What to do when a zone stalls. Defaults to MergeWait.
+	StallPolicy StallPolicy
+	// How long a zone may deliver nothing before it is considered stalled.
+	// Defaults to DefaultMergeStallTimeout.
+	StallTimeout time.Duration
+}
+
+// MergeZones fetches logs between the start and end timestamps (up to 'count'
+// logs per zone) from several zones at once, and writes them to the client's
+// destination as a single stream ordered by EdgeStartTimestamp.
+//
+// Each zone's logs must already be in timestamp order for the output to be
+// ordered, which holds for the requests endpoint but only approximately for
+// the received endpoint. Meta.ZoneCounts reports how many logs each zone
+// contributed.
+func (c *Client) MergeZones(ctx context.Context, zoneIDs []string, start int64, end int64, count int, opts *MergeOptions) (*Meta, error) {
+	o := MergeOptions{}
+	if opts != nil {
+		o = *opts
+	}
+
+	if o.BufferDepth <= 0 {
+		o.BufferDepth = DefaultMergeBufferDepth
+	}
+
+	if o.StallTimeout <= 0 {
+		o.StallTimeout = DefaultMergeStallTimeout
+	}
+
+	if len(c.fields) > 0 && !hasField(c.fields, TimestampField) {
+		return nil, errors.Errorf("%s must be in Fields to merge zones", TimestampField)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	total := &Meta{ZoneCounts: make(map[string]int)}
+	sources := make([]*mergeSource, len(zoneIDs))
+	for i, zoneID := range zoneIDs {
+		sources[i] = c.startMergeSource(ctx, zoneID, start, end, count, o.BufferDepth)
+	}
+
+	lw := c.newLogWriter(c.dest)
+	err := mergeSources(ctx, sources, &o, lw, total)
+	if cerr := lw.close(); err == nil && cerr != nil {
+		err = errors.Wrap(cerr, "failed to write logs")
+	}
+	reportTo(lw, total)
+
+	return total, err
+}
+
+func mergeSources(ctx context.Context, sources []*mergeSource, o *MergeOptions, lw logWriter, total *Meta) error {
+	for {
+		var next *mergeSource
+		for _, s := range sources {
+			if err := s.fill(ctx, o, total); err != nil {
+				return errors.Wrapf(err, "zone %s", s.zoneID)
+			}
+
+			if s.head != nil && (next == nil || s.headTime.Before(next.headTime)) {
+				next = s
+			}
+		}
+
+		if next == nil {
+			return nil
+		}
+
+		if err := lw.writeLog(next.head.Raw); err != nil {
+			return errors.Wrap(err, "failed to write log")
+		}
+		total.Count++
+		total.Bytes += int64(len(next.head.Raw)) + 1
+		total.ZoneCounts[next.zoneID]++
+		next.head = nil
+	}
+}
+
+// mergeSource is a single zone's stream of logs in a merge.
+type mergeSource struct {
+	zoneID   string
+	ch       chan StreamedRecord
+	errc     chan error
+	cancel   context.CancelFunc
+	head     *StreamedRecord
+	headTime time.Time
+	done     bool
+}
+
+func (c *Client) startMergeSource(ctx context.Context, zoneID string, start int64, end int64, count int, depth int) *mergeSource {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &mergeSource{
+		zoneID: zoneID,
+		ch:     make(chan StreamedRecord, depth),
+		errc:   make(chan error, 1),
+		cancel: cancel,
+	}
+
+	go func() {
+		defer close(s.errc)
+		defer close(s.ch)
+
+		// Logs are merged as returned by the API. Record-level options,
+		// such as Redactions, apply once to the merged stream, as it is
+		// written by MergeZones, so that sequence numbers span every zone.
+		meta, err := c.getFromTimestamp(ctx, zoneID, start, end, count, c.withZoneSample(&channelWriter{ctx: ctx, ch: s.ch}))
+		if meta != nil && meta.StatusCode == http.StatusNoContent {
+			err = nil
+		}
+		if err != nil {
+			s.errc <- err
+		}
+	}()
+
+	return s
+}
+
+// withZoneSample wraps the logWriter of a zone being merged in a recordStage
+// that applies seeded sampling, if Options.SampleSeed is set, so that the
+// zone's count caps the logs it keeps. The merged stream is sampled again by
+// the record stage of MergeZones, which keeps the same logs.
+func (c *Client) withZoneSample(lw logWriter) logWriter {
+	if !c.seeded() {
+		return lw
+	}
+
+	limit := &recordLimit{}
+	return &recordStage{forwarder: forwarder{next: lw}, funcs: []recordFunc{c.sampleFunc(), limit.apply}, limit: limit}
+}
+
+// fill makes sure the source has a head log, unless it is finished.
+func (s *mergeSource) fill(ctx context.Context, o *MergeOptions, total *Meta) error {
+	if s.head != nil || s.done {
+		return nil
+	}
+
+	var stalled <-chan time.Time
+	if o.StallPolicy == MergeDropStalled {
+		t := time.NewTimer(o.StallTimeout)
+		defer t.Stop()
+		stalled = t.C
+	}
+
+	select {
+	case r, ok := <-s.ch:
+		if !ok {
+			s.done = true
+			return <-s.errc
+		}
+		s.head = &r
+		s.headTime, _ = recordTime(r.Record, TimestampField)
+	case <-stalled:
+		s.cancel()
+		s.done = true
+		for range s.ch {
+			total.Dropped++
+		}
+		total.DroppedZones = append(total.DroppedZones, s.zoneID)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return nil
+}
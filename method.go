@@ -0,0 +1,31 @@
+package logshare
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// newRequest creates a request for u with the client's RequestMethod. With
+// POST, the query parameters are sent as a form-encoded body instead.
+func (c *Client) newRequest(u *url.URL) (*http.Request, error) {
+	if c.requestMethod != http.MethodPost {
+		return http.NewRequest(http.MethodGet, u.String(), nil)
+	}
+
+	if !c.capabilities(endpointOf(u)).AcceptsPost {
+		return nil, errors.Errorf("%s does not accept POST requests", u.Path)
+	}
+
+	get := *u
+	get.RawQuery = ""
+	req, err := http.NewRequest(http.MethodPost, get.String(), strings.NewReader(u.RawQuery))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return req, nil
+}
@@ -0,0 +1,44 @@
+package logshare
+
+import (
+	"time"
+)
+
+// MetricsRecorder receives metrics as the client works, such as to feed them
+// into Prometheus. Methods are called from the goroutines making requests,
+// so must be safe for concurrent use, and should return quickly.
+type MetricsRecorder interface {
+	// IncRequests counts a completed request with its HTTP status code, or
+	// zero if it failed before a response was received.
+	IncRequests(status int)
+	// ObserveBytes records the bytes of log data streamed by a request.
+	ObserveBytes(n int64)
+	// ObserveDuration records how long a request took, from sending it to
+	// streaming the last log.
+	ObserveDuration(d time.Duration)
+	// IncRetries counts a retry, of a backfill chunk, a Sink batch, a
+	// request that failed to connect (see Options.ConnRetries) or an empty
+	// response (see Options.RetryOnEmpty).
+	IncRetries()
+	// IncChunks counts a backfill chunk that was fetched.
+	IncChunks()
+}
+
+// NopMetrics is a MetricsRecorder that discards every metric. It is the
+// default.
+type NopMetrics struct{}
+
+// IncRequests implements MetricsRecorder.
+func (NopMetrics) IncRequests(status int) {}
+
+// ObserveBytes implements MetricsRecorder.
+func (NopMetrics) ObserveBytes(n int64) {}
+
+// ObserveDuration implements MetricsRecorder.
+func (NopMetrics) ObserveDuration(d time.Duration) {}
+
+// IncRetries implements MetricsRecorder.
+func (NopMetrics) IncRetries() {}
+
+// IncChunks implements MetricsRecorder.
+func (NopMetrics) IncChunks() {}
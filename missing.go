@@ -0,0 +1,67 @@
+package logshare
+
+import (
+	"github.com/pkg/errors"
+)
+
+// MissingFieldPolicy controls how a log that lacks one of the requested fields
+// is written. The requested fields are Options.Fields, along with the columns
+// of FormatCSV and FormatFixedWidth and the fields referred to by a
+// FormatTemplate template.
+type MissingFieldPolicy int
+
+const (
+	// MissingDefault applies the default of each format: JSON formats write
+	// the log as received, without the field; FormatCSV and FormatFixedWidth
+	// write an empty cell; FormatTemplate renders an empty string.
+	MissingDefault MissingFieldPolicy = iota
+	// MissingEmptyString adds the field with an empty string value.
+	MissingEmptyString
+	// MissingNull adds the field with a null value. Formats other than JSON
+	// write null as an empty value, as with MissingEmptyString.
+	MissingNull
+	// MissingOmit leaves the field out. Formats with fixed columns cannot
+	// omit one, so write an empty value instead.
+	MissingOmit
+	// MissingError fails the pull at the first log that lacks a field.
+	MissingError
+)
+
+// missingFieldFunc returns a recordFunc that applies the client's
+// MissingFieldPolicy to the requested fields, or nil if it has nothing to do.
+func (c *Client) missingFieldFunc() recordFunc {
+	if len(c.fields) == 0 {
+		return nil
+	}
+
+	var fill interface{}
+	switch c.missingFields {
+	case MissingEmptyString:
+		fill = ""
+	case MissingNull:
+		fill = nil
+	case MissingError:
+	default:
+		return nil
+	}
+
+	fields := c.fields
+	policy := c.missingFields
+	return func(rec LogRecord) (LogRecord, error) {
+		for _, f := range fields {
+			if _, ok := rec[f]; ok {
+				continue
+			}
+			if policy == MissingError {
+				return nil, missingField(f)
+			}
+			rec[f] = fill
+		}
+
+		return rec, nil
+	}
+}
+
+func missingField(name string) error {
+	return errors.Errorf("log has no field %q", name)
+}
@@ -0,0 +1,161 @@
+package logshare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// Output is one of the outputs of PullToOutputs: a writer and the format to
+// write to it.
+type Output struct {
+	// Identifies the output in errors and Meta.OutputBytes. Defaults to
+	// "output <n>", numbering outputs from 1.
+	Name   string
+	Format OutputFormat
+	Writer io.Writer
+}
+
+// PullToOutputs fetches the logs between the start and end timestamps (up to
+// 'count' logs) and writes them to each of outputs in its own format, in a
+// single pass: e.g. a raw NDJSON archive and a CSV for analysts. Each log is
+// decoded at most once, however many outputs need it decoded; NDJSON outputs
+// get the API's lines as-is unless a record-level option changes them. The
+// destination writer and Options.Format are not used.
+//
+// Meta.OutputBytes holds the bytes written to each output, by name. If an
+// output fails, the pull stops and the error names the output.
+func (c *Client) PullToOutputs(ctx context.Context, zoneID string, start int64, end int64, count int, outputs []Output) (*Meta, error) {
+	if len(outputs) == 0 {
+		return nil, errors.New("no outputs")
+	}
+
+	fw := &fanoutWriter{}
+	for i, o := range outputs {
+		name := o.Name
+		if name == "" {
+			name = fmt.Sprintf("output %d", i+1)
+		}
+
+		if err := c.checkFormat(o.Format); err != nil {
+			return nil, errors.Wrap(err, name)
+		}
+
+		nc := c.clone()
+		nc.format = o.Format
+		cw := &countingWriter{w: o.Writer}
+		fw.outputs = append(fw.outputs, fanoutOutput{name: name, lw: nc.newFormatWriter(cw), cw: cw})
+	}
+
+	meta, err := c.getFromTimestamp(ctx, zoneID, start, end, count, c.withReverse(c.withReorder(c.withRecordStage(fw))))
+	if meta != nil {
+		meta.OutputBytes = make(map[string]int64, len(fw.outputs))
+		for _, o := range fw.outputs {
+			meta.OutputBytes[o.name] = o.cw.n
+		}
+		if meta.StatusCode == http.StatusNoContent {
+			err = nil
+		}
+	}
+
+	return meta, err
+}
+
+type fanoutOutput struct {
+	name string
+	lw   logWriter
+	cw   *countingWriter
+}
+
+// fanoutWriter is a logWriter that writes each log to several logWriters,
+// decoding or encoding it at most once.
+type fanoutWriter struct {
+	outputs []fanoutOutput
+}
+
+func (f *fanoutWriter) writeLog(line []byte) error {
+	var rec LogRecord
+	for _, o := range f.outputs {
+		rw, ok := o.lw.(recordWriter)
+		if !ok {
+			if err := o.lw.writeLog(line); err != nil {
+				return errors.Wrapf(err, "%s failed", o.name)
+			}
+			continue
+		}
+
+		if rec == nil {
+			var err error
+			if rec, err = decodeLog(line); err != nil {
+				return err
+			}
+		}
+		if err := rw.writeRecord(rec); err != nil {
+			return errors.Wrapf(err, "%s failed", o.name)
+		}
+	}
+
+	return nil
+}
+
+func (f *fanoutWriter) writeRecord(rec LogRecord) error {
+	var line []byte
+	for _, o := range f.outputs {
+		if rw, ok := o.lw.(recordWriter); ok {
+			if err := rw.writeRecord(rec); err != nil {
+				return errors.Wrapf(err, "%s failed", o.name)
+			}
+			continue
+		}
+
+		if line == nil {
+			var err error
+			if line, err = json.Marshal(rec); err != nil {
+				return errors.Wrap(err, "failed to encode log")
+			}
+		}
+		if err := o.lw.writeLog(line); err != nil {
+			return errors.Wrapf(err, "%s failed", o.name)
+		}
+	}
+
+	return nil
+}
+
+// close finishes every output, returning the first failure.
+func (f *fanoutWriter) close() error {
+	var first error
+	for _, o := range f.outputs {
+		if err := o.lw.close(); err != nil && first == nil {
+			first = errors.Wrapf(err, "%s failed", o.name)
+		}
+	}
+
+	return first
+}
+
+func (f *fanoutWriter) report(meta *Meta) {
+	for _, o := range f.outputs {
+		reportTo(o.lw, meta)
+	}
+}
+
+func (f *fanoutWriter) markEmpty(line string) error {
+	for _, o := range f.outputs {
+		if err := markEmpty(o.lw, line); err != nil {
+			return errors.Wrapf(err, "%s failed", o.name)
+		}
+	}
+
+	return nil
+}
+
+func (f *fanoutWriter) bindMeta(meta Meta) {
+	for _, o := range f.outputs {
+		bindMeta(o.lw, &meta)
+	}
+}
@@ -0,0 +1,235 @@
+package logshare
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// getFromTimestamp fetches up to count logs from start to end. If count is
+// over the client's MaxCount, each request is clamped to MaxCount and later
+// pages are fetched from the RayID of the last log received, until count logs
+// have been written or the logs run out. Under CapPaginate, a count over the
+// ResponseCap is paged by the ResponseCap in the same way, and a count of
+// zero fetches pages until a response falls short of the ResponseCap.
+// Options.MaxWallClock bounds all of the pages together.
+func (c *Client) getFromTimestamp(ctx context.Context, zoneID string, start int64, end int64, count int, lw logWriter) (*Meta, error) {
+	ctx, cancel := c.withWallClock(ctx)
+	defer cancel()
+
+	meta, err := c.getSampledFromTimestamp(ctx, zoneID, start, end, count, lw)
+	if meta != nil {
+		meta.RequestedCount = count
+	}
+
+	return meta, err
+}
+
+// getSampledFromTimestamp applies the count to the logs kept by seeded
+// sampling, if any: the window is requested without a count, and the record
+// stage writes logs until count have made it through sampling and the other
+// record-level filters. Under Options.Reverse, the count applies to the
+// logs in the order they are written, newest first.
+func (c *Client) getSampledFromTimestamp(ctx context.Context, zoneID string, start int64, end int64, count int, lw logWriter) (*Meta, error) {
+	if !c.seeded() || count <= 0 {
+		return c.getPagesFromTimestamp(ctx, zoneID, start, end, count, lw)
+	}
+
+	endpoint := byRequest
+	if c.byReceived {
+		endpoint = byReceived
+	}
+	if c.capabilities(endpoint).Count == CountRequired {
+		return nil, errors.Errorf("a count can't cap SampleSeed sampling: the %s endpoint requires a count, which applies before sampling", endpoint)
+	}
+
+	limitRecords(lw, count)
+	return c.getPagesFromTimestamp(ctx, zoneID, start, end, 0, lw)
+}
+
+// getPagesFromTimestamp fetches up to count logs from start to end, as
+// getFromTimestamp does.
+func (c *Client) getPagesFromTimestamp(ctx context.Context, zoneID string, start int64, end int64, count int, lw logWriter) (*Meta, error) {
+	uncapped := count <= 0 && end > 0 && c.responseCap > 0 && c.capPolicy == CapPaginate
+
+	maxPage := c.maxCount
+	if c.capPolicy == CapPaginate && c.responseCap > 0 && count > c.responseCap && (maxPage <= 0 || maxPage > c.responseCap) {
+		maxPage = c.responseCap
+	}
+
+	if !uncapped && (maxPage <= 0 || count <= maxPage) {
+		u, err := c.timestampURL(ctx, zoneID, start, end, count)
+		if err != nil {
+			return nil, err
+		}
+
+		return c.request(ctx, u, lw)
+	}
+
+	if len(c.fields) > 0 && !hasField(c.fields, "RayID") {
+		if uncapped {
+			return nil, errors.New("RayID must be in Fields to paginate beyond ResponseCap")
+		}
+		return nil, errors.New("RayID must be in Fields to paginate beyond MaxCount")
+	}
+
+	pageCount := maxPage
+	if uncapped {
+		pageCount = 0
+	}
+	u, err := c.timestampURL(ctx, zoneID, start, end, pageCount)
+	if err != nil {
+		return nil, err
+	}
+
+	first := u
+	began := time.Now()
+	total := &Meta{}
+	pw := &pageWriter{forwarder: forwarder{next: lw}}
+	if !uncapped {
+		pw.max = count
+	}
+	remaining := count
+
+	for {
+		pw.n = 0
+		meta, err := c.request(ctx, u, pw)
+		total.add(meta)
+		total.Pages++
+
+		if meta != nil && meta.StatusCode == http.StatusNoContent {
+			err = nil
+		}
+		if err != nil {
+			lw.close()
+			return total, err
+		}
+
+		if meta.Partial {
+			break
+		}
+
+		if uncapped {
+			if meta.Count < c.responseCap || pw.lastRay == "" {
+				break
+			}
+		} else {
+			remaining -= pw.n
+			if remaining <= 0 || meta.Count < pageCount || pw.lastRay == "" {
+				break
+			}
+
+			// The page starts at (and includes) the last ray we've seen, so
+			// ask for one more log than remains. Should it not, pageWriter
+			// drops the extra log.
+			pageCount = remaining + 1
+			if pageCount > maxPage {
+				pageCount = maxPage
+			}
+		}
+
+		pw.skipRay = pw.lastRay
+		if u, err = c.rayIDURL(ctx, zoneID, pw.lastRay, end, pageCount); err != nil {
+			if cutShort(ctx) {
+				total.Partial = true
+				break
+			}
+			lw.close()
+			return total, err
+		}
+	}
+
+	total.Count -= pw.duplicates + pw.beyond
+	total.Duplicates += pw.duplicates
+	// Every capped page was followed by another.
+	total.CapReached = false
+
+	if c.emitSummary {
+		total.FirstRayID, total.LastRayID = pw.firstRay, pw.lastRay
+		if err := c.writeSummary(lw, first, total, time.Since(began)); err != nil {
+			lw.close()
+			return total, err
+		}
+	}
+
+	err = lw.close()
+	reportTo(lw, total)
+
+	return total, err
+}
+
+// pageWriter is a logWriter that writes pages of logs to a single logWriter,
+// tracking the RayID of the last log and skipping the boundary log that is
+// repeated at the start of the next page. Logs beyond max (if set) are
+// dropped, whether or not the API repeated the boundary log. The pages are
+// summarized as one pull, so the summary of each page is dropped, and only an
+// empty first page is marked empty.
+type pageWriter struct {
+	forwarder
+	firstRay   string
+	lastRay    string
+	skipRay    string
+	n          int
+	max        int
+	written    int
+	duplicates int
+	beyond     int
+}
+
+func (p *pageWriter) writeLog(line []byte) error {
+	var rec struct{ RayID string }
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return errors.Wrap(err, "failed to decode log")
+	}
+
+	skip := p.skipRay
+	p.skipRay = ""
+	if skip != "" && rec.RayID == skip {
+		p.duplicates++
+		return nil
+	}
+
+	if p.max > 0 && p.written >= p.max {
+		p.beyond++
+		return nil
+	}
+
+	if err := p.next.writeLog(line); err != nil {
+		return err
+	}
+	p.written++
+	if p.lastRay == "" {
+		p.firstRay = rec.RayID
+	}
+	p.lastRay = rec.RayID
+	p.n++
+
+	return nil
+}
+
+// close is a no-op: the underlying logWriter is closed once every page has
+// been written.
+func (p *pageWriter) close() error { return nil }
+
+func (p *pageWriter) writeSummary(line []byte) error { return nil }
+
+func (p *pageWriter) markEmpty(line string) error {
+	if p.lastRay != "" {
+		return nil
+	}
+
+	return markEmpty(p.next, line)
+}
+
+func hasField(fields []string, field string) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,104 @@
+package logshare
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pagedServer serves logs numbered from 0 to n-1, honouring count and
+// starting from (and including, unless exclusive) the log of start_id.
+func pagedServer(n int, exclusive bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		from := 0
+		if id := q.Get("start_id"); id != "" {
+			v, _ := strconv.ParseInt(id, 16, 64)
+			from = int(v)
+			if exclusive {
+				from++
+			}
+		}
+		to := n
+		if count, _ := strconv.Atoi(q.Get("count")); count > 0 && from+count < to {
+			to = from + count
+		}
+		for i := from; i < to; i++ {
+			fmt.Fprintf(w, "{\"RayID\":\"%016x\"}\n", i)
+		}
+	})
+}
+
+func TestPaginatedSummary(t *testing.T) {
+	var out bytes.Buffer
+	c, srv := newTestClient(t, pagedServer(5, false), &Options{MaxCount: 2, EmitSummary: true, Dest: &out})
+	defer srv.Close()
+
+	start := time.Now().Add(-time.Hour).Unix()
+	meta, err := c.GetFromTimestamp(testZoneID, start, start+60, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Pages < 2 {
+		t.Fatalf("fetched %d pages, want several", meta.Pages)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 5 || strings.Join(lines[:4], "\n")+"\n" != testLogs(4) {
+		t.Fatalf("wrote %q, want 4 logs and a summary", out.String())
+	}
+
+	var summary struct {
+		Summary    bool   `json:"logshare_summary"`
+		Count      int    `json:"count"`
+		Start      string `json:"start"`
+		FirstRayID string `json:"first_ray_id"`
+		LastRayID  string `json:"last_ray_id"`
+	}
+	if err := json.Unmarshal([]byte(lines[4]), &summary); err != nil {
+		t.Fatal(err)
+	}
+	if !summary.Summary || summary.Count != 4 || summary.Start != fmt.Sprint(start) ||
+		summary.FirstRayID != fmt.Sprintf("%016x", 0) || summary.LastRayID != fmt.Sprintf("%016x", 3) {
+		t.Errorf("summary = %s", lines[4])
+	}
+}
+
+func TestPaginatedEmptyMarker(t *testing.T) {
+	var out bytes.Buffer
+	c, srv := newTestClient(t, pagedServer(0, false), &Options{MaxCount: 2, EmitEmptyMarker: true, Dest: &out})
+	defer srv.Close()
+
+	start := time.Now().Add(-time.Hour).Unix()
+	if _, err := c.GetFromTimestamp(testZoneID, start, start+60, 4); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() == 0 {
+		t.Error("wrote nothing, want the empty marker")
+	}
+}
+
+func TestPaginatedCount(t *testing.T) {
+	for _, exclusive := range []bool{false, true} {
+		t.Run(fmt.Sprintf("exclusive=%v", exclusive), func(t *testing.T) {
+			var out bytes.Buffer
+			c, srv := newTestClient(t, pagedServer(10, exclusive), &Options{MaxCount: 2, Dest: &out})
+			defer srv.Close()
+
+			start := time.Now().Add(-time.Hour).Unix()
+			meta, err := c.GetFromTimestamp(testZoneID, start, start+60, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if out.String() != testLogs(3) || meta.Count != 3 {
+				t.Errorf("Count = %d, wrote %q", meta.Count, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package logshare
+
+import "github.com/pkg/errors"
+
+// ParamNames selects the names of the start and end query parameters, for API
+// versions that name them differently.
+type ParamNames int
+
+const (
+	// ParamNamesStartEnd names the parameters start and end. This is the
+	// default, and matches the current API.
+	ParamNamesStartEnd ParamNames = iota
+	// ParamNamesStartTimeEndTime names the parameters start_time and
+	// end_time.
+	ParamNamesStartTimeEndTime
+)
+
+// validate returns an error for an unknown ParamNames.
+func (p ParamNames) validate() error {
+	switch p {
+	case ParamNamesStartEnd, ParamNamesStartTimeEndTime:
+		return nil
+	}
+
+	return errors.Errorf("unknown ParamNames %d", p)
+}
+
+// startParam returns the name of the query parameter for the start timestamp.
+func (c *Client) startParam() string {
+	if c.paramNames == ParamNamesStartTimeEndTime {
+		return "start_time"
+	}
+
+	return "start"
+}
+
+// endParam returns the name of the query parameter for the end timestamp.
+func (c *Client) endParam() string {
+	if c.paramNames == ParamNamesStartTimeEndTime {
+		return "end_time"
+	}
+
+	return "end"
+}
@@ -0,0 +1,142 @@
+package logshare
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// PartitionFunc returns the path of the file a log is written to.
+type PartitionFunc func(rec LogRecord) (string, error)
+
+// PartitionedWrite fetches the logs between start and end, and writes each
+// to the file named by partition, in the client's output format. Files (and
+// their directories) are created as needed, appended to if they exist, and
+// closed once the pull completes; Options.Dest is not used. Each file is
+// written as a separate output, so with FormatCSV each has its own header.
+// Meta.Partitions counts the logs written to each file.
+func (c *Client) PartitionedWrite(ctx context.Context, zoneID string, start time.Time, end time.Time, partition PartitionFunc) (*Meta, error) {
+	u, err := c.timestampURL(ctx, zoneID, start.Unix(), end.Unix(), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	pw := &partitionWriter{
+		client:    c,
+		partition: partition,
+		files:     make(map[string]*partitionFile),
+		counts:    make(map[string]int),
+	}
+
+	meta, err := c.request(ctx, u, c.withRecordStage(pw))
+	if meta != nil && meta.StatusCode == http.StatusNoContent {
+		err = nil
+	}
+
+	return meta, err
+}
+
+// DailyPartitionedWrite is a PartitionedWrite that splits logs into a file
+// per day, by EdgeStartTimestamp (in UTC), which must be in Fields if Fields
+// are set. pathLayout is a time layout for the path of each day's file, such
+// as "logs/2006/01/02.jsonl" or "logs/2006-01-02.csv". Windows that span
+// midnight are split between the files of both days.
+func (c *Client) DailyPartitionedWrite(ctx context.Context, zoneID string, start time.Time, end time.Time, pathLayout string) (*Meta, error) {
+	if len(c.fields) > 0 && !hasField(c.fields, TimestampField) {
+		return nil, errors.Errorf("%s must be in Fields to partition logs by day", TimestampField)
+	}
+
+	return c.PartitionedWrite(ctx, zoneID, start, end, func(rec LogRecord) (string, error) {
+		ts, ok := recordTime(rec, TimestampField)
+		if !ok {
+			return "", errors.Errorf("log has no valid %s", TimestampField)
+		}
+
+		return ts.UTC().Format(pathLayout), nil
+	})
+}
+
+// partitionWriter is a logWriter that routes each log to the output for its
+// partition.
+type partitionWriter struct {
+	client    *Client
+	partition PartitionFunc
+	files     map[string]*partitionFile
+	counts    map[string]int
+}
+
+type partitionFile struct {
+	f  *os.File
+	lw logWriter
+}
+
+func (p *partitionWriter) writeLog(line []byte) error {
+	rec, err := decodeLog(line)
+	if err != nil {
+		return err
+	}
+
+	return p.writeRecord(rec)
+}
+
+func (p *partitionWriter) writeRecord(rec LogRecord) error {
+	path, err := p.partition(rec)
+	if err != nil {
+		return errors.Wrap(err, "failed to partition log")
+	}
+
+	pf, ok := p.files[path]
+	if !ok {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+
+		pf = &partitionFile{f: f, lw: p.client.newFormatWriter(f)}
+		p.files[path] = pf
+	}
+
+	p.counts[path]++
+	return writeRecordTo(pf.lw, rec)
+}
+
+// close finishes the output of every partition and closes its file.
+func (p *partitionWriter) close() error {
+	var firstErr error
+	for path, pf := range p.files {
+		err := pf.lw.close()
+		if cerr := pf.f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil && firstErr == nil {
+			firstErr = errors.Wrapf(err, "failed to write %s", path)
+		}
+	}
+
+	return firstErr
+}
+
+func (p *partitionWriter) report(meta *Meta) {
+	if len(p.counts) == 0 {
+		return
+	}
+
+	if meta.Partitions == nil {
+		meta.Partitions = make(map[string]int, len(p.counts))
+	}
+	for path, n := range p.counts {
+		meta.Partitions[path] += n
+	}
+
+	for _, pf := range p.files {
+		reportTo(pf.lw, meta)
+	}
+}
@@ -0,0 +1,173 @@
+package logshare
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// digestCompression is the compression of the t-digest used by Percentiles.
+// It keeps at most a few hundred centroids, however many values are added.
+const digestCompression = 100
+
+// Percentiles fetches the logs between the start and end timestamps (up to
+// 'count' logs) and returns approximate percentiles of a numeric field, such
+// as OriginResponseTime, for each of ps (from 0 to 100). Values are
+// summarized in a t-digest as they are read, so memory use is bounded
+// however many logs there are; the tails are estimated most accurately.
+// Record-level options (such as HostFilter) apply, so only the logs they
+// keep are summarized. Logs without a numeric value for the field are
+// skipped. Meta.SampleSize is the number of values summarized; with none,
+// every percentile is NaN.
+func (c *Client) Percentiles(ctx context.Context, zoneID string, start int64, end int64, count int, field string, ps []float64) (map[float64]float64, *Meta, error) {
+	for _, p := range ps {
+		if p < 0 || p > 100 || math.IsNaN(p) {
+			return nil, nil, errors.Errorf("invalid percentile %v: must be between 0 and 100", p)
+		}
+	}
+
+	pw := &percentileWriter{field: field, digest: newTDigest(digestCompression)}
+	lw := c.withRecordStage(pw)
+
+	nc := c.withSingleField(field, lw != logWriter(pw))
+	meta, err := nc.getFromTimestamp(ctx, zoneID, start, end, count, lw)
+	if meta != nil && meta.StatusCode == http.StatusNoContent {
+		err = nil
+	}
+	if err != nil {
+		return nil, meta, err
+	}
+	meta.SampleSize = pw.n
+
+	out := make(map[float64]float64, len(ps))
+	for _, p := range ps {
+		out[p] = pw.digest.quantile(p / 100)
+	}
+
+	return out, meta, nil
+}
+
+// percentileWriter is a logWriter that adds the values of a numeric field to
+// a t-digest.
+type percentileWriter struct {
+	field  string
+	digest *tdigest
+	n      int
+}
+
+func (p *percentileWriter) writeLog(line []byte) error {
+	rec, err := decodeLog(line)
+	if err != nil {
+		return err
+	}
+
+	if v, ok := Record(rec).GetFloat(p.field); ok {
+		p.digest.add(v)
+		p.n++
+	}
+
+	return nil
+}
+
+func (p *percentileWriter) close() error { return nil }
+
+// tdigest is a merging t-digest: a sketch of a distribution as a bounded
+// number of weighted centroids, which are smallest near the tails.
+type tdigest struct {
+	compression float64
+	centroids   []centroid
+	buf         []centroid
+	total       float64
+	min, max    float64
+}
+
+type centroid struct {
+	mean, weight float64
+}
+
+func newTDigest(compression float64) *tdigest {
+	return &tdigest{compression: compression, min: math.Inf(1), max: math.Inf(-1)}
+}
+
+func (t *tdigest) add(x float64) {
+	t.buf = append(t.buf, centroid{x, 1})
+	t.total++
+	t.min = math.Min(t.min, x)
+	t.max = math.Max(t.max, x)
+
+	if len(t.buf) >= 5*int(t.compression) {
+		t.compress()
+	}
+}
+
+// compress merges the buffered values into the centroids, merging adjacent
+// centroids while their combined weight stays within the size limit at
+// their quantile.
+func (t *tdigest) compress() {
+	if len(t.buf) == 0 {
+		return
+	}
+
+	all := append(t.centroids, t.buf...)
+	sort.Slice(all, func(i, j int) bool { return all[i].mean < all[j].mean })
+	t.buf = t.buf[:0]
+
+	merged := make([]centroid, 0, len(t.centroids)+1)
+	cur := all[0]
+	var before float64
+	for _, c := range all[1:] {
+		w := cur.weight + c.weight
+		q := (before + w/2) / t.total
+		if w <= 4*t.total*q*(1-q)/t.compression {
+			cur.mean += (c.mean - cur.mean) * c.weight / w
+			cur.weight = w
+			continue
+		}
+
+		before += cur.weight
+		merged = append(merged, cur)
+		cur = c
+	}
+	t.centroids = append(merged, cur)
+}
+
+// quantile returns the estimated value at quantile q (from 0 to 1),
+// interpolating between the centres of the centroids around it.
+func (t *tdigest) quantile(q float64) float64 {
+	t.compress()
+	if len(t.centroids) == 0 {
+		return math.NaN()
+	}
+	if q <= 0 {
+		return t.min
+	}
+	if q >= 1 {
+		return t.max
+	}
+
+	target := q * t.total
+	prevMean, prevPos := t.min, 0.0
+	var cum float64
+	for _, c := range t.centroids {
+		pos := cum + c.weight/2
+		if target <= pos {
+			return interpolate(prevMean, c.mean, prevPos, pos, target)
+		}
+		prevMean, prevPos = c.mean, pos
+		cum += c.weight
+	}
+
+	return interpolate(prevMean, t.max, prevPos, t.total, target)
+}
+
+// interpolate returns the value at pos on the line from (p0, v0) to (p1, v1).
+func interpolate(v0 float64, v1 float64, p0 float64, p1 float64, pos float64) float64 {
+	if p1 <= p0 {
+		return v1
+	}
+
+	return v0 + (v1-v0)*(pos-p0)/(p1-p0)
+}
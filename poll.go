@@ -0,0 +1,116 @@
+package logshare
+
+import (
+	"container/heap"
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SortByTimestamp sorts logs by EdgeStartTimestamp, oldest first. Logs without
+// a timestamp sort first. The sort is stable.
+func SortByTimestamp(records []LogRecord) {
+	timed := make([]timedRecord, len(records))
+	for i, rec := range records {
+		ts, _ := recordTime(rec, TimestampField)
+		timed[i] = timedRecord{ts, rec}
+	}
+
+	sort.SliceStable(timed, func(i, j int) bool {
+		return timed[i].ts.Before(timed[j].ts)
+	})
+
+	for i, t := range timed {
+		records[i] = t.rec
+	}
+}
+
+// PollWindow fetches the logs from the last lookback of available logs (see
+// WindowLast) and returns the newest topN of them, sorted by
+// EdgeStartTimestamp, oldest first. It is intended for dashboards that poll a
+// short window: only topN logs are held in memory, however many the window
+// contains.
+func (c *Client) PollWindow(ctx context.Context, zoneID string, lookback time.Duration, topN int) ([]LogRecord, *Meta, error) {
+	if topN <= 0 {
+		return nil, nil, errors.New("topN must be greater than zero")
+	}
+
+	if len(c.fields) > 0 && !hasField(c.fields, TimestampField) {
+		return nil, nil, errors.Errorf("%s must be in Fields to sort logs", TimestampField)
+	}
+
+	start, end := c.WindowLast(lookback)
+	u, err := c.timestampURL(ctx, zoneID, start.Unix(), end.Unix(), 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	top := &newestWriter{n: topN}
+	meta, err := c.request(ctx, u, c.withRecordStage(top))
+	if meta != nil && meta.StatusCode == http.StatusNoContent {
+		err = nil
+	}
+	if err != nil {
+		return nil, meta, err
+	}
+
+	records := make([]LogRecord, len(top.h))
+	for i, r := range top.h {
+		records[i] = r.rec
+	}
+	SortByTimestamp(records)
+
+	return records, meta, nil
+}
+
+// newestWriter is a logWriter that keeps the newest n logs, using a min-heap
+// ordered by timestamp.
+type newestWriter struct {
+	n int
+	h timedHeap
+}
+
+func (t *newestWriter) writeLog(line []byte) error {
+	rec, err := decodeLog(line)
+	if err != nil {
+		return err
+	}
+
+	return t.writeRecord(rec)
+}
+
+func (t *newestWriter) writeRecord(rec LogRecord) error {
+	ts, _ := recordTime(rec, TimestampField)
+	if len(t.h) < t.n {
+		heap.Push(&t.h, timedRecord{ts, rec})
+	} else if ts.After(t.h[0].ts) {
+		t.h[0] = timedRecord{ts, rec}
+		heap.Fix(&t.h, 0)
+	}
+
+	return nil
+}
+
+func (t *newestWriter) close() error { return nil }
+
+type timedRecord struct {
+	ts  time.Time
+	rec LogRecord
+}
+
+// timedHeap is a min-heap of logs by timestamp.
+type timedHeap []timedRecord
+
+func (h timedHeap) Len() int            { return len(h) }
+func (h timedHeap) Less(i, j int) bool  { return h[i].ts.Before(h[j].ts) }
+func (h timedHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *timedHeap) Push(x interface{}) { *h = append(*h, x.(timedRecord)) }
+func (h *timedHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,60 @@
+package logshare
+
+import (
+	"time"
+)
+
+// DefaultRecordProgressInterval is the number of logs between calls to
+// Options.RecordProgress when no RecordProgressInterval is set.
+const DefaultRecordProgressInterval = 1000
+
+// RecordProgressFunc is called as logs are written, with the number of logs
+// written so far by the request and the newest EdgeStartTimestamp among them,
+// so that a UI can show how far a pull has caught up. latest is zero if no
+// log had a timestamp.
+type RecordProgressFunc func(records int, latest time.Time)
+
+// progress calls the client's RecordProgressFunc every few logs, and once
+// more with the total when the request ends.
+type progress struct {
+	fn       RecordProgressFunc
+	every    int
+	n        int
+	reported int
+	latest   time.Time
+}
+
+// newProgress returns a progress for the client's RecordProgressFunc, or nil
+// if there is none.
+func (c *Client) newProgress() *progress {
+	if c.recordProgress == nil {
+		return nil
+	}
+
+	return &progress{fn: c.recordProgress, every: c.progressInterval}
+}
+
+func (p *progress) apply(rec LogRecord) (LogRecord, error) {
+	if ts, ok := recordTime(rec, TimestampField); ok && ts.After(p.latest) {
+		p.latest = ts
+	}
+
+	p.n++
+	if p.n%p.every == 0 {
+		p.report()
+	}
+
+	return rec, nil
+}
+
+// done reports the total, unless it was the last count reported.
+func (p *progress) done() {
+	if p != nil && p.n != p.reported {
+		p.report()
+	}
+}
+
+func (p *progress) report() {
+	p.reported = p.n
+	p.fn(p.n, p.latest)
+}
@@ -0,0 +1,56 @@
+package logshare
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecordProgress(t *testing.T) {
+	tests := []struct {
+		name  string
+		every int
+		want  []int
+	}{
+		{name: "final call with the total", every: 2, want: []int{2, 4, 5}},
+		{name: "total already reported", every: 5, want: []int{5}},
+		{name: "fewer logs than the interval", every: 10, want: []int{5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for i := 1; i <= 5; i++ {
+					fmt.Fprintln(w, testLog(fmt.Sprint(i), int64(i)))
+				}
+			})
+
+			var got []int
+			var latest time.Time
+			c, ts := newTestClient(t, handler, &Options{
+				Dest: &bytes.Buffer{},
+				RecordProgress: func(records int, ts time.Time) {
+					got = append(got, records)
+					latest = ts
+				},
+				RecordProgressInterval: tt.every,
+			})
+			defer ts.Close()
+
+			start := time.Now().Add(-time.Hour).Unix()
+			if _, err := c.GetFromTimestamp(testZoneID, start, start+60, 0); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("progress at %v, want %v", got, tt.want)
+			}
+			if !latest.Equal(time.Unix(5, 0)) {
+				t.Errorf("latest = %v, want %v", latest, time.Unix(5, 0))
+			}
+		})
+	}
+}
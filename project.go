@@ -0,0 +1,101 @@
+package logshare
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// validateProjection checks that every field of Options.ProjectFields is
+// written by the client: one of Fields (as renamed), or a field the client
+// adds. Without Fields, the fields aren't known and any projection is allowed.
+func (c *Client) validateProjection() error {
+	if c.project == nil || c.fields == nil || c.flatten {
+		return nil
+	}
+
+	known := map[string]bool{c.sequenceField: c.sequence, c.ingestField: c.ingestField != ""}
+	for _, f := range c.fields {
+		if !c.dropped(f) {
+			known[c.renamed(f)] = true
+		}
+	}
+
+	for _, f := range c.project {
+		if !known[f] {
+			return errors.Errorf("ProjectFields: %q is not in Fields", f)
+		}
+	}
+
+	return nil
+}
+
+// withProjection wraps lw in a projectWriter if Options.ProjectFields is set.
+func (c *Client) withProjection(lw logWriter) logWriter {
+	if c.project == nil {
+		return lw
+	}
+
+	return &projectWriter{forwarder: forwarder{next: lw}, fields: c.project}
+}
+
+// projectWriter is a logWriter that writes only the given fields of each log,
+// as a JSON object with its keys in that order. Fields that a log lacks are
+// left out.
+type projectWriter struct {
+	forwarder
+	fields []string
+	buf    bytes.Buffer
+}
+
+func (p *projectWriter) writeLog(line []byte) error {
+	rec, err := decodeLog(line)
+	if err != nil {
+		return err
+	}
+
+	return p.writeRecord(rec)
+}
+
+func (p *projectWriter) writeRecord(rec LogRecord) error {
+	// A writer that takes decoded logs, such as an envelope, gets them as a
+	// map, so the order of the fields is lost.
+	if rw, ok := p.next.(recordWriter); ok {
+		out := make(LogRecord, len(p.fields))
+		for _, f := range p.fields {
+			if v, ok := rec[f]; ok {
+				out[f] = v
+			}
+		}
+		return rw.writeRecord(out)
+	}
+
+	p.buf.Reset()
+	p.buf.WriteByte('{')
+	for _, f := range p.fields {
+		v, ok := rec[f]
+		if !ok {
+			continue
+		}
+
+		if p.buf.Len() > 1 {
+			p.buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(f)
+		val, err := json.Marshal(v)
+		if err != nil {
+			return errors.Wrap(err, "failed to encode log")
+		}
+		p.buf.Write(key)
+		p.buf.WriteByte(':')
+		p.buf.Write(val)
+	}
+	p.buf.WriteByte('}')
+
+	return p.next.writeLog(p.buf.Bytes())
+}
+
+func (p *projectWriter) close() error {
+	return p.next.close()
+}
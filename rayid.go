@@ -0,0 +1,252 @@
+package logshare
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrRayIDTime is returned by RayIDTime when no plausible time can be read
+// from a ray ID.
+var ErrRayIDTime = errors.New("could not determine a time from the ray ID")
+
+// The range of times RayIDTime considers plausible.
+var rayIDEpoch = time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// RayIDTime returns the approximate time encoded in a ray ID, such as
+// "5a2f5c6ad16c23a1", or the same with a datacenter suffix such as "-SJC".
+//
+// The ray ID format is not documented by Cloudflare, and RayIDTime is a
+// heuristic: it treats the high 32 bits of the 16 hex digit ID as Unix
+// seconds, so at best it is accurate to the second. The datacenter suffix is
+// ignored as it carries no time information. If the result is before 2010 or
+// in the future, as it is for many real ray IDs, the ID does not follow this
+// layout and ErrRayIDTime is returned. Use the result to bound a window
+// where it is plausible, never as a log's timestamp. GetFromRayID uses it to
+// check its end timestamp, unless Options.SkipRayIDTimeCheck is set.
+func RayIDTime(rayID string) (time.Time, error) {
+	if i := strings.IndexByte(rayID, '-'); i >= 0 {
+		rayID = rayID[:i]
+	}
+
+	if len(rayID) != 16 {
+		return time.Time{}, errors.Wrapf(ErrRayIDTime, "malformed ray ID %q", rayID)
+	}
+
+	id, err := strconv.ParseUint(rayID, 16, 64)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(ErrRayIDTime, "malformed ray ID %q", rayID)
+	}
+
+	t := time.Unix(int64(id>>32), 0)
+	if t.Before(rayIDEpoch) || t.After(time.Now().Add(time.Hour)) {
+		return time.Time{}, ErrRayIDTime
+	}
+
+	return t, nil
+}
+
+// unixSeconds converts a Unix timestamp in seconds, milliseconds,
+// microseconds or nanoseconds to seconds, going by its magnitude.
+func unixSeconds(ts int64) int64 {
+	switch {
+	case ts > 1e17:
+		return ts / 1e9
+	case ts > 1e14:
+		return ts / 1e6
+	case ts > 1e11:
+		return ts / 1e3
+	}
+
+	return ts
+}
+
+// DefaultRayIDWorkers is the number of ray IDs GetByRayIDs fetches at once.
+const DefaultRayIDWorkers = 4
+
+// GetSingleByRayID fetches the log for a single ray ID.
+func (c *Client) GetSingleByRayID(zoneID string, rayID string) (*Meta, error) {
+	ctx := context.Background()
+	u, err := c.singleRayURL(ctx, zoneID, rayID)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.request(ctx, u, c.newLogWriter(c.dest))
+}
+
+func (c *Client) singleRayURL(ctx context.Context, zoneID string, rayID string) (*url.URL, error) {
+	if !validRayID(rayID) {
+		return nil, errors.Errorf("invalid ray ID %q", rayID)
+	}
+
+	zoneID, err := c.resolveZone(zoneID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.checkFields(ctx, zoneID); err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(fmt.Sprintf("%s/zones/%s/logs/rayids/%s", c.endpoint, zoneID, rayID))
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	c.setFields(params)
+	if c.timestampFormat != "" {
+		params.Set("timestamps", c.timestampFormat)
+	}
+	u.RawQuery = params.Encode()
+
+	return u, nil
+}
+
+// GetByRayIDs fetches the logs for each of the given ray IDs, a few at a time,
+// and writes them to w. Duplicate IDs are fetched once. Meta.FoundRayIDs and
+// Meta.MissingRayIDs report which IDs had a log.
+//
+// Every ID is validated before any are fetched. Logs are written in the order
+// their requests complete, not the order of rayIDs.
+func (c *Client) GetByRayIDs(ctx context.Context, zoneID string, rayIDs []string, w io.Writer) (*Meta, error) {
+	var ids, invalid []string
+	seen := make(map[string]bool, len(rayIDs))
+	for _, id := range rayIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		if !validRayID(id) {
+			invalid = append(invalid, id)
+		}
+		ids = append(ids, id)
+	}
+
+	if len(invalid) > 0 {
+		return nil, errors.Errorf("invalid ray IDs: %s", strings.Join(invalid, ", "))
+	}
+
+	total := &Meta{}
+	lw := c.newLogWriter(w)
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+
+	work := make(chan string)
+	for i := 0; i < DefaultRayIDWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range work {
+				found, meta, err := c.fetchRayID(ctx, zoneID, id, lw, &mu)
+
+				mu.Lock()
+				total.add(meta)
+				switch {
+				case err != nil && firstErr == nil:
+					firstErr = errors.Wrapf(err, "ray ID %s", id)
+				case found:
+					total.FoundRayIDs = append(total.FoundRayIDs, id)
+				case err == nil:
+					total.MissingRayIDs = append(total.MissingRayIDs, id)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for _, id := range ids {
+		select {
+		case work <- id:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	if err := lw.close(); firstErr == nil && err != nil {
+		firstErr = errors.Wrap(err, "failed to write logs")
+	}
+	reportTo(lw, total)
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+
+	return total, firstErr
+}
+
+// fetchRayID fetches the log for a single ray ID into memory, then writes it
+// to lw while holding mu.
+func (c *Client) fetchRayID(ctx context.Context, zoneID string, rayID string, lw logWriter, mu *sync.Mutex) (bool, *Meta, error) {
+	u, err := c.singleRayURL(ctx, zoneID, rayID)
+	if err != nil {
+		return false, nil, err
+	}
+
+	buf := &lineBuffer{}
+	meta, err := c.request(ctx, u, buf)
+	if meta != nil && (meta.StatusCode == http.StatusNotFound || meta.StatusCode == http.StatusNoContent) {
+		return false, meta, nil
+	}
+	if err != nil {
+		return false, meta, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, line := range buf.lines {
+		if err := lw.writeLog(line); err != nil {
+			return false, meta, errors.Wrap(err, "failed to write log")
+		}
+	}
+
+	return len(buf.lines) > 0, meta, nil
+}
+
+// lineBuffer is a logWriter that keeps a copy of every line in memory.
+type lineBuffer struct {
+	lines [][]byte
+}
+
+func (l *lineBuffer) writeLog(line []byte) error {
+	l.lines = append(l.lines, append([]byte(nil), line...))
+	return nil
+}
+
+func (l *lineBuffer) close() error { return nil }
+
+// validRayID reports whether id looks like a ray ID: 16 hex digits, with an
+// optional datacenter suffix such as "-SJC".
+func validRayID(id string) bool {
+	if i := strings.IndexByte(id, '-'); i >= 0 {
+		suffix := id[i+1:]
+		if len(suffix) == 0 || len(suffix) > 4 {
+			return false
+		}
+		id = id[:i]
+	}
+
+	if len(id) != 16 {
+		return false
+	}
+
+	_, err := strconv.ParseUint(id, 16, 64)
+	return err == nil
+}
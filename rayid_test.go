@@ -0,0 +1,84 @@
+package logshare
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestRayIDTime(t *testing.T) {
+	tests := []struct {
+		rayID string
+		want  time.Time
+		err   bool
+	}{
+		{rayID: "5a2f5c6ad16c23a1", want: time.Unix(0x5a2f5c6a, 0)},
+		{rayID: "5a2f5c6ad16c23a1-SJC", want: time.Unix(0x5a2f5c6a, 0)},
+		// Real ray IDs often don't follow the layout.
+		{rayID: "3a2f5c6ad16c23a1", err: true},
+		{rayID: "ffffffffd16c23a1", err: true},
+		{rayID: "3a2f5c6a", err: true},
+		{rayID: "zz2f5c6ad16c23a1", err: true},
+	}
+
+	for _, tt := range tests {
+		got, err := RayIDTime(tt.rayID)
+		if tt.err {
+			if errors.Cause(err) != ErrRayIDTime {
+				t.Errorf("RayIDTime(%q) = %v, %v; want ErrRayIDTime", tt.rayID, got, err)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("RayIDTime(%q) = %v, %v; want %v", tt.rayID, got, err, tt.want)
+		}
+	}
+}
+
+func TestUnixSeconds(t *testing.T) {
+	const sec = 1700000000
+	tests := []struct {
+		ts   int64
+		want int64
+	}{
+		{sec, sec},
+		{sec * 1e3, sec},
+		{sec * 1e6, sec},
+		{sec * 1e9, sec},
+	}
+
+	for _, tt := range tests {
+		if got := unixSeconds(tt.ts); got != tt.want {
+			t.Errorf("unixSeconds(%d) = %d, want %d", tt.ts, got, tt.want)
+		}
+	}
+}
+
+func TestGetFromRayIDEndCheck(t *testing.T) {
+	const rayID = "5a2f5c6ad16c23a1"
+	before := int64(0x5a2f5c6a - 60)
+
+	for _, skip := range []bool{false, true} {
+		requests := 0
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.Write([]byte(testLogs(1)))
+		})
+		c, srv := newTestClient(t, handler, &Options{SkipRayIDTimeCheck: skip, Dest: ioutil.Discard})
+
+		_, err := c.GetFromRayID(testZoneID, rayID, before, 0)
+		srv.Close()
+
+		if skip {
+			if err != nil || requests != 1 {
+				t.Errorf("with SkipRayIDTimeCheck: err = %v after %d requests, want a request", err, requests)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), "is not after ray ID") || requests != 0 {
+			t.Errorf("err = %v after %d requests, want the end to be rejected", err, requests)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package logshare
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// recordFunc transforms a decoded log before it is written. Returning a nil
+// record drops the log.
+type recordFunc func(rec LogRecord) (LogRecord, error)
+
+// recordWriter is implemented by logWriters that can write a decoded log
+// directly, saving a round trip through JSON.
+type recordWriter interface {
+	writeRecord(rec LogRecord) error
+}
+
+// recordFuncs returns the record-level transforms configured on the client,
+// in the order they are applied. Any per-pull state (such as the ingestion
+// time) is fixed when recordFuncs is called. Logs are numbered by seq,
+// redacted by red, capped by limit and counted by prog, if they are non-nil.
+func (c *Client) recordFuncs(seq *sequencer, red *redactor, limit *recordLimit, prog *progress) []recordFunc {
+	var fns []recordFunc
+
+	if fn := c.sampleFunc(); fn != nil {
+		fns = append(fns, fn)
+	}
+
+	if fn := c.missingFieldFunc(); fn != nil {
+		fns = append(fns, fn)
+	}
+
+	if red != nil {
+		fns = append(fns, red.apply)
+	}
+
+	if fn := c.renameFunc(); fn != nil {
+		fns = append(fns, fn)
+	}
+
+	if c.ingestField != "" {
+		field := c.ingestField
+		now := time.Now().UTC().Format(time.RFC3339)
+		policy := c.duplicates
+		fns = append(fns, func(rec LogRecord) (LogRecord, error) {
+			return rec, setField(rec, field, now, policy)
+		})
+	}
+
+	if c.flatten {
+		fns = append(fns, flattenRecord(c.flattenArrays, c.flattenDepth, c.duplicates))
+	}
+
+	// After every filter, so that only the logs written count, and before
+	// numbering, so that the logs beyond the cap take no numbers.
+	if limit != nil {
+		fns = append(fns, limit.apply)
+	}
+
+	fns = seq.wrap(fns)
+
+	if prog != nil {
+		fns = append(fns, prog.apply)
+	}
+
+	return fns
+}
+
+// withRecordStage wraps lw in a recordStage if the client has any
+// record-level transforms configured, and returns lw unchanged otherwise.
+func (c *Client) withRecordStage(lw logWriter) logWriter {
+	return c.withSharedRecordStage(lw, c.newSequencer())
+}
+
+// withSharedRecordStage is like withRecordStage, but numbers logs with seq
+// rather than a sequencer of its own, for operations made of several pulls.
+func (c *Client) withSharedRecordStage(lw logWriter, seq *sequencer) logWriter {
+	red := c.newRedactor()
+	var limit *recordLimit
+	if c.seeded() {
+		limit = &recordLimit{}
+	}
+	prog := c.newProgress()
+	fns := c.recordFuncs(seq, red, limit, prog)
+	if len(fns) == 0 && c.deadLetter == nil && c.hostFilter == "" {
+		return lw
+	}
+
+	return &recordStage{forwarder: forwarder{next: lw}, funcs: fns, redactor: red, limit: limit, progress: prog, deadLetter: c.deadLetter, host: newHostFilter(c.hostFilter)}
+}
+
+// outputColumns returns the columns of tabular output: Options.ProjectFields,
+// or else the requested fields (less any dropped by a redaction, and as
+// renamed), plus any fields added by the client.
+func (c *Client) outputColumns() []string {
+	if c.project != nil {
+		return c.project
+	}
+
+	// Flattened keys aren't known until the first log is read.
+	if c.fields == nil || c.flatten {
+		return nil
+	}
+
+	var cols []string
+	if c.sequence {
+		cols = append(cols, c.sequenceField)
+	}
+
+	for _, f := range c.fields {
+		if !c.dropped(f) {
+			cols = append(cols, c.renamed(f))
+		}
+	}
+
+	if c.ingestField != "" && !hasField(cols, c.ingestField) {
+		cols = append(cols, c.ingestField)
+	}
+
+	return cols
+}
+
+// recordStage is a logWriter that drops the logs of other hosts than
+// Options.HostFilter, then decodes each log and applies a series of
+// recordFuncs before passing it on. Logs are re-encoded as JSON (with sorted
+// keys) unless the next logWriter accepts decoded logs.
+type recordStage struct {
+	forwarder
+	funcs    []recordFunc
+	redactor *redactor
+	limit    *recordLimit
+	progress *progress
+	host     *hostFilter
+
+	deadLetter   *deadLetter
+	deadLettered int
+}
+
+func (r *recordStage) writeLog(line []byte) error {
+	if !r.host.matchLine(line) {
+		return nil
+	}
+
+	if len(r.funcs) == 0 && r.deadLetter == nil {
+		return r.next.writeLog(line)
+	}
+
+	rec, err := decodeLog(line)
+	if err != nil {
+		return r.reject(line, nil, err)
+	}
+
+	if len(r.funcs) == 0 {
+		// Only decoded to catch invalid logs for the dead-letter writer.
+		return r.next.writeLog(line)
+	}
+
+	return r.transform(line, rec)
+}
+
+func (r *recordStage) writeRecord(rec LogRecord) error {
+	if !r.host.matchRecord(rec) {
+		return nil
+	}
+
+	return r.transform(nil, rec)
+}
+
+// transform applies the recordFuncs to rec, whose raw log is line, if known.
+func (r *recordStage) transform(line []byte, rec LogRecord) error {
+	for _, fn := range r.funcs {
+		out, err := fn(rec)
+		if err != nil {
+			return r.reject(line, rec, err)
+		}
+		if out == nil {
+			return nil
+		}
+		rec = out
+	}
+
+	return writeRecordTo(r.next, rec)
+}
+
+func (r *recordStage) limitRecords(n int) {
+	if r.limit != nil {
+		r.limit.limit = n
+	}
+}
+
+func (r *recordStage) close() error {
+	err := r.next.close()
+	r.progress.done()
+
+	return err
+}
+
+func (r *recordStage) report(meta *Meta) {
+	meta.DeadLettered += r.deadLettered
+	r.host.report(meta)
+	r.redactor.report(meta)
+	reportTo(r.next, meta)
+}
+
+// writeRecordTo writes a decoded log to lw, encoding it as JSON if lw does not
+// accept decoded logs.
+func writeRecordTo(lw logWriter, rec LogRecord) error {
+	if rw, ok := lw.(recordWriter); ok {
+		return rw.writeRecord(rec)
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode log")
+	}
+
+	return lw.writeLog(line)
+}
+
+// DuplicatePolicy controls what happens when an option adds a field to a log
+// that already has a field of that name.
+type DuplicatePolicy int
+
+const (
+	// DuplicateError fails the pull. This is the default, so that a collision
+	// never silently corrupts the output.
+	DuplicateError DuplicatePolicy = iota
+	// DuplicateOverwrite replaces the existing value.
+	DuplicateOverwrite
+	// DuplicateRename keeps the existing value, and adds the new one under the
+	// first free name of the form "<name>_1", "<name>_2" and so on.
+	DuplicateRename
+)
+
+// setField adds a field to rec, applying policy if the field already exists.
+func setField(rec LogRecord, key string, v interface{}, policy DuplicatePolicy) error {
+	if _, ok := rec[key]; !ok {
+		rec[key] = v
+		return nil
+	}
+
+	switch policy {
+	case DuplicateOverwrite:
+		rec[key] = v
+		return nil
+	case DuplicateRename:
+		for i := 1; ; i++ {
+			k := key + "_" + strconv.Itoa(i)
+			if _, ok := rec[k]; !ok {
+				rec[k] = v
+				return nil
+			}
+		}
+	}
+
+	return errors.Errorf("field %q already exists in log", key)
+}
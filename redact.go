@@ -0,0 +1,118 @@
+package logshare
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"unicode/utf8"
+)
+
+// RedactAction is what a Redaction does to a field.
+type RedactAction int
+
+const (
+	// RedactDrop removes the field.
+	RedactDrop RedactAction = iota
+	// RedactHash replaces the value with the hex HMAC-SHA256 of it, keyed
+	// with Options.RedactionSalt, so that equal values are pseudonymized
+	// alike across pulls that use the same salt.
+	RedactHash
+	// RedactMask replaces each character of the value with '*', except for
+	// KeepLeading characters at the start and KeepTrailing at the end.
+	// Values too short to keep that many are masked entirely.
+	RedactMask
+)
+
+// Redaction describes how to redact a field (see Options.Redactions).
+type Redaction struct {
+	Action RedactAction
+	// The number of characters RedactMask leaves at each end of the value.
+	KeepLeading  int
+	KeepTrailing int
+}
+
+// redactor applies the client's Redactions to logs, counting the logs
+// affected for each field.
+type redactor struct {
+	redactions map[string]Redaction
+	salt       []byte
+	counts     map[string]int
+}
+
+func (c *Client) newRedactor() *redactor {
+	if len(c.redactions) == 0 {
+		return nil
+	}
+
+	return &redactor{
+		redactions: c.redactions,
+		salt:       []byte(c.redactionSalt),
+		counts:     make(map[string]int),
+	}
+}
+
+func (r *redactor) apply(rec LogRecord) (LogRecord, error) {
+	for field, red := range r.redactions {
+		v, ok := rec[field]
+		if !ok || v == nil {
+			continue
+		}
+		r.counts[field]++
+
+		switch red.Action {
+		case RedactDrop:
+			delete(rec, field)
+		case RedactHash:
+			mac := hmac.New(sha256.New, r.salt)
+			mac.Write([]byte(csvValue(v)))
+			rec[field] = hex.EncodeToString(mac.Sum(nil))
+		case RedactMask:
+			rec[field] = maskValue(csvValue(v), red.KeepLeading, red.KeepTrailing)
+		}
+	}
+
+	return rec, nil
+}
+
+// report adds the number of logs redacted per field to meta. A nil redactor
+// reports nothing.
+func (r *redactor) report(meta *Meta) {
+	if r == nil || len(r.counts) == 0 {
+		return
+	}
+
+	if meta.Redacted == nil {
+		meta.Redacted = make(map[string]int, len(r.counts))
+	}
+	for field, n := range r.counts {
+		meta.Redacted[field] += n
+	}
+}
+
+// dropped reports whether field is removed by a RedactDrop redaction.
+func (c *Client) dropped(field string) bool {
+	red, ok := c.redactions[field]
+	return ok && red.Action == RedactDrop
+}
+
+func maskValue(s string, leading int, trailing int) string {
+	n := utf8.RuneCountInString(s)
+	if leading < 0 {
+		leading = 0
+	}
+	if trailing < 0 {
+		trailing = 0
+	}
+	if leading+trailing >= n {
+		return strings.Repeat("*", n)
+	}
+
+	runes := []rune(s)
+	var b strings.Builder
+	b.WriteString(string(runes[:leading]))
+	b.WriteString(strings.Repeat("*", n-leading-trailing))
+	b.WriteString(string(runes[n-trailing:]))
+
+	return b.String()
+}
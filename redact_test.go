@@ -0,0 +1,67 @@
+package logshare
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRedactionsApplyToEveryPath checks that the pulls that don't write to the
+// destination still redact logs.
+func TestRedactionsApplyToEveryPath(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"ClientIP":"192.0.2.1","RayID":"a"}`)
+	})
+	c, srv := newTestClient(t, handler, &Options{
+		Redactions: map[string]Redaction{"ClientIP": {Action: RedactDrop}},
+	})
+	defer srv.Close()
+
+	start := time.Now().Add(-time.Hour).Unix()
+	end := start + 60
+	ctx := context.Background()
+
+	paths := map[string]func() (string, error){
+		"OpenFromTimestamp": func() (string, error) {
+			s, err := c.OpenFromTimestamp(ctx, testZoneID, start, end, 0)
+			if err != nil {
+				return "", err
+			}
+			defer s.Close()
+
+			var buf bytes.Buffer
+			_, err = buf.ReadFrom(s)
+			return buf.String(), err
+		},
+		"PullAndForward": func() (string, error) {
+			var buf bytes.Buffer
+			_, err := c.PullAndForward(ctx, testZoneID, start, end, 0, func(line []byte) ([]byte, error) {
+				return line, nil
+			}, &buf)
+			return buf.String(), err
+		},
+		"ExtractField": func() (string, error) {
+			var buf bytes.Buffer
+			w := bufio.NewWriter(&buf)
+			_, err := c.ExtractField(ctx, testZoneID, start, end, 0, "ClientIP", w)
+			w.Flush()
+			return buf.String(), err
+		},
+	}
+
+	for name, pull := range paths {
+		out, err := pull()
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if strings.Contains(out, "192.0.2.1") {
+			t.Errorf("%s wrote a redacted value: %q", name, out)
+		}
+	}
+}
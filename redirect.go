@@ -0,0 +1,73 @@
+package logshare
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// RedirectPolicy controls how the client handles HTTP redirects, which the
+// API itself never sends but proxies in between might.
+type RedirectPolicy int
+
+const (
+	// RedirectError fails a request that is redirected. This is the default,
+	// so that credentials are never sent anywhere but the API.
+	RedirectError RedirectPolicy = iota
+	// RedirectFollowWithoutAuth follows redirects, removing the credentials
+	// from the redirected request.
+	RedirectFollowWithoutAuth
+	// RedirectFollowWithAuth follows redirects, sending the credentials to
+	// the redirect target even if it is on another host.
+	RedirectFollowWithAuth
+)
+
+// maxRedirects is the number of redirects followed before giving up, as in
+// net/http.
+const maxRedirects = 10
+
+// ErrRedirect is returned when a request is redirected under RedirectError.
+// Use errors.Cause to check for it.
+var ErrRedirect = errors.New("unexpected redirect")
+
+// authHeaders are the request headers that carry credentials.
+var authHeaders = []string{"Authorization", "X-Auth-Key", "X-Auth-Email"}
+
+// withRedirectPolicy returns a copy of hc that handles redirects according to
+// policy. A client with a CheckRedirect of its own is returned as-is.
+func withRedirectPolicy(hc *http.Client, policy RedirectPolicy) *http.Client {
+	if hc.CheckRedirect != nil {
+		return hc
+	}
+
+	nc := *hc
+	nc.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		switch policy {
+		case RedirectError:
+			// Leave any credentials out of the error.
+			u := *req.URL
+			u.User = nil
+			return errors.Wrapf(ErrRedirect, "to %s", u.String())
+		case RedirectFollowWithAuth:
+			// net/http drops Authorization when redirected to another host,
+			// but keeps the legacy headers; restore them all alike.
+			for _, h := range authHeaders {
+				if v := via[0].Header.Get(h); v != "" {
+					req.Header.Set(h, v)
+				}
+			}
+		default:
+			for _, h := range authHeaders {
+				req.Header.Del(h)
+			}
+		}
+
+		if len(via) >= maxRedirects {
+			return errors.Errorf("stopped after %d redirects", maxRedirects)
+		}
+
+		return nil
+	}
+
+	return &nc
+}
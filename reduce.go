@@ -0,0 +1,66 @@
+package logshare
+
+import (
+	"context"
+	"net/http"
+)
+
+// ReduceFunc folds a log into an accumulator, returning the new accumulator.
+// Returning an error stops the pull.
+type ReduceFunc func(acc interface{}, rec LogRecord) (interface{}, error)
+
+// Reduce fetches the logs between the start and end timestamps (up to
+// 'count' logs) and folds them, as they are read, into an accumulator
+// starting from initial, for aggregations such as counts, sums and top-Ks
+// without holding the logs in memory. It returns the final accumulator, which
+// is initial if the window is empty; after a failure, it is the accumulator
+// as of the last log folded. Record-level options are applied before each
+// log is folded. Meta.Reduced is the number of logs folded.
+//
+// The pull stops with the context's error if it is cancelled.
+func (c *Client) Reduce(ctx context.Context, zoneID string, start int64, end int64, count int, initial interface{}, reducer ReduceFunc) (interface{}, *Meta, error) {
+	rw := &reduceWriter{ctx: ctx, acc: initial, fn: reducer}
+	meta, err := c.getFromTimestamp(ctx, zoneID, start, end, count, c.withRecordStage(rw))
+	if meta != nil {
+		meta.Reduced = rw.n
+		if meta.StatusCode == http.StatusNoContent {
+			err = nil
+		}
+	}
+
+	return rw.acc, meta, err
+}
+
+// reduceWriter is a logWriter that folds logs into an accumulator.
+type reduceWriter struct {
+	ctx context.Context
+	acc interface{}
+	fn  ReduceFunc
+	n   int
+}
+
+func (r *reduceWriter) writeLog(line []byte) error {
+	rec, err := decodeLog(line)
+	if err != nil {
+		return err
+	}
+
+	return r.writeRecord(rec)
+}
+
+func (r *reduceWriter) writeRecord(rec LogRecord) error {
+	if err := r.ctx.Err(); err != nil {
+		return err
+	}
+
+	acc, err := r.fn(r.acc, rec)
+	if err != nil {
+		return err
+	}
+	r.acc = acc
+	r.n++
+
+	return nil
+}
+
+func (r *reduceWriter) close() error { return nil }
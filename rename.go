@@ -0,0 +1,79 @@
+package logshare
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// validateRenames checks that renames don't map two fields to the same name,
+// or a field to the name of another requested field, and, if fields are set,
+// that every renamed field is requested.
+func validateRenames(renames map[string]string, fields []string) error {
+	sources := make([]string, 0, len(renames))
+	for from := range renames {
+		sources = append(sources, from)
+	}
+	sort.Strings(sources)
+
+	targets := make(map[string]string, len(renames))
+	for _, from := range sources {
+		to := renames[from]
+		if to == "" {
+			return errors.Errorf("RenameFields: field %q is renamed to an empty name", from)
+		}
+
+		if len(fields) > 0 && !hasField(fields, from) {
+			return errors.Errorf("RenameFields: field %q is not in Fields", from)
+		}
+
+		if other, ok := targets[to]; ok {
+			return errors.Errorf("RenameFields: fields %q and %q are both renamed to %q", other, from, to)
+		}
+		targets[to] = from
+
+		if _, renamed := renames[to]; !renamed && hasField(fields, to) {
+			return errors.Errorf("RenameFields: field %q is renamed to %q, which is also in Fields", from, to)
+		}
+	}
+
+	return nil
+}
+
+// renameFunc returns a recordFunc that applies the client's RenameFields, or
+// nil if there are none.
+func (c *Client) renameFunc() recordFunc {
+	if len(c.renames) == 0 {
+		return nil
+	}
+
+	renames, policy := c.renames, c.duplicates
+	return func(rec LogRecord) (LogRecord, error) {
+		// Remove every renamed field before adding any, so that fields can
+		// swap names.
+		values := make(map[string]interface{}, len(renames))
+		for from := range renames {
+			if v, ok := rec[from]; ok {
+				values[from] = v
+				delete(rec, from)
+			}
+		}
+
+		for from, v := range values {
+			if err := setField(rec, renames[from], v, policy); err != nil {
+				return nil, err
+			}
+		}
+
+		return rec, nil
+	}
+}
+
+// renamed returns the output name of a field.
+func (c *Client) renamed(field string) string {
+	if to, ok := c.renames[field]; ok {
+		return to
+	}
+
+	return field
+}
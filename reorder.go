@@ -0,0 +1,134 @@
+package logshare
+
+import (
+	"container/heap"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ReceivedOrder controls what is done about the order of logs fetched
+// ByReceived, which the API returns in the order they were processed rather
+// than by EdgeStartTimestamp.
+type ReceivedOrder int
+
+const (
+	// ReceivedAsIs writes logs in the order they are received. This is the
+	// default.
+	ReceivedAsIs ReceivedOrder = iota
+	// ReceivedMeasure writes logs in the order they are received, and
+	// reports how far out of order they were in Meta.MaxReorderSkew, for
+	// sizing a ReorderBuffer.
+	ReceivedMeasure
+	// ReceivedReorder holds up to Options.ReorderBuffer logs and writes them
+	// oldest first by EdgeStartTimestamp. Logs that arrive further out of
+	// order than the buffer can hold are still written out of order;
+	// Meta.MaxReorderSkew reports how far out of order logs arrived.
+	ReceivedReorder
+)
+
+// DefaultReorderBuffer is the number of logs held by ReceivedReorder when no
+// Options.ReorderBuffer is set.
+const DefaultReorderBuffer = 10000
+
+// validate returns an error for an unknown ReceivedOrder.
+func (o ReceivedOrder) validate() error {
+	switch o {
+	case ReceivedAsIs, ReceivedMeasure, ReceivedReorder:
+		return nil
+	}
+
+	return errors.Errorf("unknown ReceivedOrder %d", o)
+}
+
+// withReorder wraps lw in a reorderWriter if logs are fetched ByReceived and
+// their order is to be measured or corrected.
+func (c *Client) withReorder(lw logWriter) logWriter {
+	if !c.byReceived || c.receivedOrder == ReceivedAsIs {
+		return lw
+	}
+
+	r := &reorderWriter{forwarder: forwarder{next: lw}}
+	if c.receivedOrder == ReceivedReorder {
+		r.size = c.reorderBuffer
+	}
+
+	return r
+}
+
+// reorderWriter is a logWriter that measures how far out of order logs
+// arrive, and, with a size, reorders them by timestamp through a min-heap of
+// up to size logs.
+type reorderWriter struct {
+	forwarder
+	size   int
+	h      timedHeap
+	latest time.Time
+	skew   time.Duration
+}
+
+func (r *reorderWriter) writeLog(line []byte) error {
+	if r.size == 0 {
+		var rec struct{ EdgeStartTimestamp json.RawMessage }
+		if err := json.Unmarshal(line, &rec); err == nil {
+			if ts, ok := rawTime(rec.EdgeStartTimestamp); ok {
+				r.observe(ts)
+			}
+		}
+
+		return r.next.writeLog(line)
+	}
+
+	rec, err := decodeLog(line)
+	if err != nil {
+		return err
+	}
+
+	return r.writeRecord(rec)
+}
+
+func (r *reorderWriter) writeRecord(rec LogRecord) error {
+	ts, ok := recordTime(rec, TimestampField)
+	if ok {
+		r.observe(ts)
+	}
+
+	if r.size == 0 {
+		return writeRecordTo(r.next, rec)
+	}
+
+	heap.Push(&r.h, timedRecord{ts, rec})
+	if len(r.h) <= r.size {
+		return nil
+	}
+
+	return writeRecordTo(r.next, heap.Pop(&r.h).(timedRecord).rec)
+}
+
+// observe records how far ts is behind the latest timestamp seen so far.
+func (r *reorderWriter) observe(ts time.Time) {
+	if ts.After(r.latest) {
+		r.latest = ts
+	} else if d := r.latest.Sub(ts); d > r.skew {
+		r.skew = d
+	}
+}
+
+func (r *reorderWriter) close() error {
+	for len(r.h) > 0 {
+		if err := writeRecordTo(r.next, heap.Pop(&r.h).(timedRecord).rec); err != nil {
+			r.next.close()
+			return err
+		}
+	}
+
+	return r.next.close()
+}
+
+func (r *reorderWriter) report(meta *Meta) {
+	if r.skew > meta.MaxReorderSkew {
+		meta.MaxReorderSkew = r.skew
+	}
+	reportTo(r.next, meta)
+}
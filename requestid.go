@@ -0,0 +1,36 @@
+package logshare
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// DefaultRequestIDHeader is the header a request ID is sent in when
+// Options.RequestIDHeader is not set.
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// requestID returns the ID to send with a request, or an empty string if
+// request IDs are not enabled.
+func (c *Client) requestID() string {
+	if !c.sendRequestID {
+		return ""
+	}
+
+	if c.requestIDFunc != nil {
+		return c.requestIDFunc()
+	}
+
+	return newUUID()
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
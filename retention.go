@@ -0,0 +1,39 @@
+package logshare
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultRetentionWindow is how long logs are assumed to be retained by the
+// API when no Options.RetentionWindow is set.
+const DefaultRetentionWindow = 7 * 24 * time.Hour
+
+// retentionSlack is how far past the retention window a pull may start
+// before it fails, so that a start computed as exactly the window before now
+// (as by a caller pulling "the last 7 days") is not rejected because the
+// clock moved on, or the local clock runs ahead of the API's.
+const retentionSlack = time.Minute
+
+// ErrBeyondRetention is returned when a pull starts before the retention
+// window, so its logs have likely expired.
+var ErrBeyondRetention = errors.New("start is older than the retention window")
+
+// checkRetention returns ErrBeyondRetention if start (in Unix seconds or
+// nanoseconds) is older than the client's retention window, give or take
+// retentionSlack.
+func (c *Client) checkRetention(start int64) error {
+	if c.retention <= 0 {
+		return nil
+	}
+
+	oldest := c.now().Add(-c.retention)
+	t := time.Unix(unixSeconds(start), 0)
+	if t.Before(oldest.Add(-retentionSlack)) {
+		return errors.Wrapf(ErrBeyondRetention, "logs from %s have likely expired (retention is %s, so the oldest available are from %s); set Options.RetentionWindow if your plan retains logs for longer",
+			t.UTC().Format(time.RFC3339), c.retention, oldest.UTC().Format(time.RFC3339))
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package logshare
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestCheckRetention(t *testing.T) {
+	c, err := New("token", "", "", &Options{RetentionWindow: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	tests := []struct {
+		name  string
+		start time.Time
+		err   error
+	}{
+		{name: "within the window", start: now.Add(-time.Hour)},
+		{name: "exactly the window", start: now.Add(-24 * time.Hour)},
+		{name: "within the slack", start: now.Add(-24*time.Hour - 30*time.Second)},
+		{name: "beyond the slack", start: now.Add(-24*time.Hour - 2*time.Minute), err: ErrBeyondRetention},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := c.checkRetention(tt.start.Unix()); errors.Cause(err) != tt.err {
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+			if err := c.checkRetention(tt.start.UnixNano()); errors.Cause(err) != tt.err {
+				t.Errorf("nanoseconds: err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package logshare
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrRetryBudgetExhausted is returned when a backfill has used up its shared
+// retry budget.
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+// retryable reports whether a failed request is worth retrying: transport
+// errors, rate limiting and server errors are, other client errors are not.
+func retryable(ctx context.Context, meta *Meta, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+
+	if meta == nil {
+		return true
+	}
+
+	return meta.StatusCode == http.StatusTooManyRequests || meta.StatusCode >= 500
+}
+
+// retryBudget is a count of retries shared by every request in an operation,
+// so that a systemic failure fails fast rather than each request retrying in
+// full. A budget of zero (or less) is unlimited.
+type retryBudget struct {
+	mu        sync.Mutex
+	limit     int
+	used      int
+	exhausted bool
+}
+
+func newRetryBudget(limit int) *retryBudget {
+	return &retryBudget{limit: limit}
+}
+
+// take consumes a retry, reporting false if none are left.
+func (b *retryBudget) take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.limit > 0 && b.used >= b.limit {
+		b.exhausted = true
+		return false
+	}
+	b.used++
+
+	return true
+}
+
+// remaining returns the number of retries left, or -1 when unlimited.
+func (b *retryBudget) remaining() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.limit <= 0 {
+		return -1
+	}
+
+	return b.limit - b.used
+}
+
+func (b *retryBudget) isExhausted() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.exhausted
+}
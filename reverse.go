@@ -0,0 +1,199 @@
+package logshare
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultMaxResponseBytes is the most log data buffered for Options.Reverse
+// when no MaxResponseBytes is set.
+const DefaultMaxResponseBytes = 64 << 20
+
+// ErrResponseTooLarge is returned when a response that must be buffered is
+// larger than Options.MaxResponseBytes.
+var ErrResponseTooLarge = errors.New("response exceeds MaxResponseBytes")
+
+// withReverse wraps lw in a reverseWriter if Options.Reverse is set.
+func (c *Client) withReverse(lw logWriter) logWriter {
+	if !c.reverse {
+		return lw
+	}
+
+	r := &reverseWriter{forwarder: forwarder{next: lw}, max: c.maxResponseBytes}
+	if c.spill {
+		r.spill = &spillFile{dir: c.spillDir}
+	}
+
+	return r
+}
+
+// reverseWriter is a logWriter that buffers every log, then writes them to the
+// next logWriter newest first when closed. Logs beyond max bytes are spilled
+// to a temporary file, if spill is set, and read back in turn.
+type reverseWriter struct {
+	forwarder
+	max   int64
+	bytes int64
+	recs  []reverseEntry
+	spill *spillFile
+}
+
+// reverseEntry is a buffered log: either decoded in rec, or the line at off
+// in the spill file.
+type reverseEntry struct {
+	ts  time.Time
+	rec LogRecord
+	off int64
+	n   int
+}
+
+func (r *reverseWriter) writeLog(line []byte) error {
+	rec, err := decodeLog(line)
+	if err != nil {
+		return err
+	}
+	ts, _ := recordTime(rec, TimestampField)
+
+	r.bytes += int64(len(line)) + 1
+	if r.bytes > r.max {
+		if r.spill == nil {
+			return errors.Wrapf(ErrResponseTooLarge, "buffered %d logs", len(r.recs))
+		}
+
+		// Only the raw line is kept, and decoded again once read back.
+		off, err := r.spill.write(line)
+		if err != nil {
+			return err
+		}
+		r.recs = append(r.recs, reverseEntry{ts: ts, off: off, n: len(line)})
+
+		return nil
+	}
+
+	r.recs = append(r.recs, reverseEntry{ts: ts, rec: rec})
+
+	return nil
+}
+
+func (r *reverseWriter) close() error {
+	err := r.flush()
+	if r.spill != nil {
+		if serr := r.spill.remove(); err == nil {
+			err = serr
+		}
+	}
+	r.recs = nil
+
+	if cerr := r.next.close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+func (r *reverseWriter) flush() error {
+	if r.spill != nil {
+		if err := r.spill.sync(); err != nil {
+			return err
+		}
+	}
+
+	// Logs with equal timestamps keep the order they arrived in.
+	sort.SliceStable(r.recs, func(i, j int) bool {
+		return r.recs[i].ts.After(r.recs[j].ts)
+	})
+
+	var line []byte
+	for i, e := range r.recs {
+		r.recs[i].rec = nil
+		if e.rec != nil {
+			if err := writeRecordTo(r.next, e.rec); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if cap(line) < e.n {
+			line = make([]byte, e.n)
+		}
+		line = line[:e.n]
+		if _, err := r.spill.f.ReadAt(line, e.off); err != nil {
+			return errors.Wrap(err, "failed to read spilled log")
+		}
+		rec, err := decodeLog(line)
+		if err != nil {
+			return errors.Wrap(err, "failed to read spilled log")
+		}
+		if err := writeRecordTo(r.next, rec); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *reverseWriter) report(meta *Meta) {
+	if r.spill != nil {
+		meta.SpilledBytes += r.spill.size - r.spill.reported
+		r.spill.reported = r.spill.size
+	}
+	reportTo(r.next, meta)
+}
+
+// spillFile is a temporary file holding the logs a reverseWriter can't keep
+// in memory. It is created on the first write and removed once the logs have
+// been written.
+type spillFile struct {
+	dir  string
+	f    *os.File
+	w    *bufio.Writer
+	size int64
+	// How much of size has been reported in a Meta.
+	reported int64
+}
+
+// write appends line to the file, returning its offset.
+func (s *spillFile) write(line []byte) (int64, error) {
+	if s.f == nil {
+		f, err := ioutil.TempFile(s.dir, "logshare-spill-")
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to create spill file")
+		}
+		s.f = f
+		s.w = bufio.NewWriter(f)
+	}
+
+	off := s.size
+	if _, err := s.w.Write(line); err != nil {
+		return 0, errors.Wrap(err, "failed to spill log")
+	}
+	s.size += int64(len(line))
+
+	return off, nil
+}
+
+// sync flushes buffered writes, so that the logs can be read back.
+func (s *spillFile) sync() error {
+	if s.w == nil {
+		return nil
+	}
+
+	return errors.Wrap(s.w.Flush(), "failed to spill log")
+}
+
+func (s *spillFile) remove() error {
+	if s.f == nil {
+		return nil
+	}
+
+	name := s.f.Name()
+	s.f.Close()
+	s.f, s.w = nil, nil
+
+	return errors.Wrap(os.Remove(name), "failed to remove spill file")
+}
@@ -0,0 +1,54 @@
+package logshare
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestReverseSpillMatchesMemory(t *testing.T) {
+	logs := []string{
+		`{"RayID":"a","EdgeStartTimestamp":1}`,
+		// A nested field of the same name must not be taken for the
+		// timestamp.
+		`{"Meta":{"EdgeStartTimestamp":9},"RayID":"b","EdgeStartTimestamp":3}`,
+		`{"RayID":"c","EdgeStartTimestamp":2}`,
+	}
+
+	dir, err := ioutil.TempDir("", "logshare")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	reverse := func(max int64) string {
+		var buf bytes.Buffer
+		r := &reverseWriter{forwarder: forwarder{next: &ndjsonWriter{w: &buf}}, max: max, spill: &spillFile{dir: dir}}
+		for _, l := range logs {
+			if err := r.writeLog([]byte(l)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := r.close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	want := `{"EdgeStartTimestamp":3,"Meta":{"EdgeStartTimestamp":9},"RayID":"b"}` + "\n" +
+		`{"EdgeStartTimestamp":2,"RayID":"c"}` + "\n" +
+		`{"EdgeStartTimestamp":1,"RayID":"a"}` + "\n"
+	if got := reverse(1 << 20); got != want {
+		t.Errorf("in memory: wrote\n%s\nwant\n%s", got, want)
+	}
+	if got := reverse(1); got != want {
+		t.Errorf("spilled: wrote\n%s\nwant\n%s", got, want)
+	}
+
+	r := &reverseWriter{forwarder: forwarder{next: &ndjsonWriter{w: ioutil.Discard}}, max: 1, spill: &spillFile{dir: dir}}
+	defer r.close()
+	if err := r.writeLog([]byte(`{"RayID":`)); err == nil {
+		t.Error("spilled an invalid log")
+	}
+}
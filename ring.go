@@ -0,0 +1,150 @@
+package logshare
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+)
+
+// RingOptions bounds the logs kept by a RingBuffer. Logs are evicted oldest
+// first once any limit is exceeded; at least one should be set.
+type RingOptions struct {
+	// How far behind the newest log's EdgeStartTimestamp a log is kept.
+	MaxAge time.Duration
+	// The most logs kept.
+	MaxRecords int
+	// The most bytes of logs kept, as returned by the API.
+	MaxBytes int64
+}
+
+// RingBuffer keeps the most recent logs in memory, as a bounded cache of the
+// last few minutes of logs for debugging. It is an io.Writer of newline
+// delimited logs, so it can be used as Options.Dest, and is fed by
+// FollowRing. It is safe to read from while it is being written to.
+type RingBuffer struct {
+	opts    RingOptions
+	mu      sync.Mutex
+	entries []ringEntry
+	head    int
+	bytes   int64
+	newest  time.Time
+	partial []byte
+}
+
+type ringEntry struct {
+	ts   time.Time
+	rec  LogRecord
+	size int64
+}
+
+// NewRingBuffer returns an empty RingBuffer bounded by opts.
+func NewRingBuffer(opts RingOptions) *RingBuffer {
+	return &RingBuffer{opts: opts}
+}
+
+// FollowRing follows the logs of a zone into ring, as Follow does into the
+// destination writer. Logs are passed to ring as NDJSON, whatever the
+// client's output format, and without any Options.EnvelopeFunc.
+func (c *Client) FollowRing(ctx context.Context, zoneID string, start time.Time, ring *RingBuffer, opts *FollowOptions) (*Meta, error) {
+	nc := c.withNDJSON()
+	nc.dest = ring
+
+	return nc.Follow(ctx, zoneID, start, opts)
+}
+
+// Write implements io.Writer. Partial lines are buffered until their newline
+// is written. Lines that are not valid logs are skipped.
+func (r *RingBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			r.partial = append(r.partial, p...)
+			break
+		}
+
+		line := p[:i]
+		if len(r.partial) > 0 {
+			r.partial = append(r.partial, line...)
+			line = r.partial
+		}
+
+		if rec, err := decodeLog(line); err == nil {
+			r.add(rec, int64(len(line))+1)
+		}
+		r.partial = r.partial[:0]
+		p = p[i+1:]
+	}
+
+	return n, nil
+}
+
+func (r *RingBuffer) add(rec LogRecord, size int64) {
+	ts, _ := recordTime(rec, TimestampField)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = append(r.entries, ringEntry{ts, rec, size})
+	r.bytes += size
+	if ts.After(r.newest) {
+		r.newest = ts
+	}
+	r.evict()
+}
+
+// evict drops the oldest logs until the buffer is within its limits.
+func (r *RingBuffer) evict() {
+	for r.head < len(r.entries) {
+		e := r.entries[r.head]
+		over := (r.opts.MaxRecords > 0 && len(r.entries)-r.head > r.opts.MaxRecords) ||
+			(r.opts.MaxBytes > 0 && r.bytes > r.opts.MaxBytes) ||
+			(r.opts.MaxAge > 0 && !e.ts.IsZero() && r.newest.Sub(e.ts) > r.opts.MaxAge)
+		if !over {
+			break
+		}
+
+		r.entries[r.head] = ringEntry{}
+		r.bytes -= e.size
+		r.head++
+	}
+
+	// Reclaim the evicted prefix once it makes up half the slice.
+	if r.head > 0 && r.head >= len(r.entries)/2 {
+		n := copy(r.entries, r.entries[r.head:])
+		r.entries = r.entries[:n]
+		r.head = 0
+	}
+}
+
+// Len returns the number of logs in the buffer.
+func (r *RingBuffer) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.entries) - r.head
+}
+
+// Snapshot returns the logs in the buffer, oldest first. The logs are shared
+// with the buffer and must not be modified.
+func (r *RingBuffer) Snapshot() []LogRecord {
+	return r.Query(func(LogRecord) bool { return true })
+}
+
+// Query returns the logs in the buffer that match, oldest first. The logs
+// are shared with the buffer and must not be modified. match is called with
+// the buffer locked, so it must not call back into the buffer.
+func (r *RingBuffer) Query(match func(LogRecord) bool) []LogRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var recs []LogRecord
+	for _, e := range r.entries[r.head:] {
+		if match(e.rec) {
+			recs = append(recs, e.rec)
+		}
+	}
+
+	return recs
+}
@@ -0,0 +1,44 @@
+package logshare
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFollowRingIgnoresFormat(t *testing.T) {
+	sec := time.Now().Add(-time.Minute).Unix()
+	srv := &followServer{polls: [][]string{{testLog("a", sec), testLog("b", sec+1)}}}
+	c, ts := newTestClient(t, srv, &Options{
+		Format: FormatCSV,
+		EnvelopeFunc: func(rec LogRecord, meta *Meta) interface{} {
+			return map[string]interface{}{"log": rec}
+		},
+	})
+	defer ts.Close()
+
+	ring := NewRingBuffer(RingOptions{})
+	_, err := c.FollowRing(context.Background(), testZoneID, time.Unix(sec, 0), ring, &FollowOptions{
+		Lag:             time.Second,
+		PollInterval:    time.Millisecond,
+		MaxTotalRecords: 2,
+		MaxDuration:     10 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recs := ring.Snapshot()
+	if len(recs) != 2 {
+		t.Fatalf("ring holds %d logs, want 2", len(recs))
+	}
+	for i, want := range []string{"a", "b"} {
+		if recs[i]["RayID"] != want {
+			t.Errorf("log %d = %v, want RayID %s", i, recs[i], want)
+		}
+	}
+
+	if c.envelope == nil || c.format != FormatCSV {
+		t.Error("FollowRing changed the client's own settings")
+	}
+}
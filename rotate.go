@@ -0,0 +1,235 @@
+package logshare
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultBlockRecords is the default number of logs per indexed block in a
+// RotatingGzipWriter.
+const DefaultBlockRecords = 1000
+
+// RotateOptions configures a RotatingGzipWriter. A new file is started when
+// either limit is reached; with neither set, all logs go to a single file.
+type RotateOptions struct {
+	// The maximum number of logs per file.
+	MaxRecords int
+	// The maximum number of uncompressed bytes per file.
+	MaxBytes int64
+	// The number of logs per indexed block. Defaults to DefaultBlockRecords.
+	BlockRecords int
+}
+
+// RotatingGzipWriter is an io.WriteCloser that writes newline delimited logs
+// to a series of gzip compressed files named <prefix>-000001.jsonl.gz,
+// <prefix>-000002.jsonl.gz and so on, rotating between files at log
+// boundaries. It can be used as Options.Dest, and is intended for
+// FormatNDJSON: every file then holds whole logs and is self-contained.
+//
+// Each file is written as a series of gzip members, one per block of
+// BlockRecords logs, which standard tools decompress as a single stream. A
+// sidecar <file>.idx index is written alongside each file, with one line per
+// block:
+//
+//	<offset>,<length>,<records>
+//
+// where offset and length are the position and size in bytes of the block's
+// gzip member within the compressed file. A reader can seek to offset, read
+// length bytes and decompress them on their own to get the block's logs.
+type RotatingGzipWriter struct {
+	dir     string
+	prefix  string
+	opts    RotateOptions
+	seq     int
+	partial []byte
+
+	file  *os.File
+	index *os.File
+	cw    *countingWriter
+	gz    *gzip.Writer
+
+	fileRecords  int
+	fileBytes    int64
+	blockRecords int
+	blockStart   int64
+
+	// The files written so far.
+	Files []string
+}
+
+// NewRotatingGzipWriter returns a RotatingGzipWriter that creates files in
+// dir. No file is created until the first log is written.
+func NewRotatingGzipWriter(dir string, prefix string, opts *RotateOptions) *RotatingGzipWriter {
+	r := &RotatingGzipWriter{dir: dir, prefix: prefix}
+	if opts != nil {
+		r.opts = *opts
+	}
+
+	if r.opts.BlockRecords <= 0 {
+		r.opts.BlockRecords = DefaultBlockRecords
+	}
+
+	return r
+}
+
+// Write implements io.Writer. Partial lines are buffered until their newline
+// is written, so that logs are never split across files. If a log fails to
+// be written, Write returns the bytes of the logs written before it, and the
+// failed log is dropped, along with any part of it buffered by earlier calls.
+func (r *RotatingGzipWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			r.partial = append(r.partial, p...)
+			break
+		}
+
+		line := p[:i+1]
+		if len(r.partial) > 0 {
+			r.partial = append(r.partial, line...)
+			line = r.partial
+		}
+
+		if err := r.writeRecord(line); err != nil {
+			r.partial = r.partial[:0]
+			return n - len(p), err
+		}
+		r.partial = r.partial[:0]
+		p = p[i+1:]
+	}
+
+	return n, nil
+}
+
+// Close writes any trailing partial line and closes the current file.
+//
+// The file is closed, and its last gzip member finished, even if writing the
+// partial line fails, so Close should always be called, including after a
+// pull fails part way. A file cut short by a failed pull is then still valid
+// gzip, which decompresses to every log written before the failure.
+func (r *RotatingGzipWriter) Close() error {
+	var err error
+	if len(r.partial) > 0 {
+		err = r.writeRecord(r.partial)
+		r.partial = nil
+	}
+
+	if cerr := r.closeFile(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+func (r *RotatingGzipWriter) writeRecord(line []byte) error {
+	if r.file == nil {
+		if err := r.openFile(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := r.gz.Write(line); err != nil {
+		return errors.Wrap(err, "failed to write compressed log")
+	}
+	r.fileRecords++
+	r.fileBytes += int64(len(line))
+	r.blockRecords++
+
+	if r.blockRecords >= r.opts.BlockRecords {
+		if err := r.endBlock(); err != nil {
+			return err
+		}
+	}
+
+	if (r.opts.MaxRecords > 0 && r.fileRecords >= r.opts.MaxRecords) ||
+		(r.opts.MaxBytes > 0 && r.fileBytes >= r.opts.MaxBytes) {
+		return r.closeFile()
+	}
+
+	return nil
+}
+
+func (r *RotatingGzipWriter) openFile() error {
+	r.seq++
+	name := filepath.Join(r.dir, fmt.Sprintf("%s-%06d.jsonl.gz", r.prefix, r.seq))
+
+	f, err := os.Create(name)
+	if err != nil {
+		return errors.Wrap(err, "failed to create output file")
+	}
+
+	idx, err := os.Create(name + ".idx")
+	if err != nil {
+		f.Close()
+		return errors.Wrap(err, "failed to create index file")
+	}
+
+	r.file = f
+	r.index = idx
+	r.cw = &countingWriter{w: f}
+	r.gz = gzip.NewWriter(r.cw)
+	r.fileRecords, r.fileBytes, r.blockRecords, r.blockStart = 0, 0, 0, 0
+	r.Files = append(r.Files, name)
+
+	return nil
+}
+
+// endBlock finishes the current gzip member and records it in the index.
+func (r *RotatingGzipWriter) endBlock() error {
+	if r.blockRecords == 0 {
+		return nil
+	}
+
+	if err := r.gz.Close(); err != nil {
+		return errors.Wrap(err, "failed to finish compressed block")
+	}
+
+	_, err := fmt.Fprintf(r.index, "%d,%d,%d\n", r.blockStart, r.cw.n-r.blockStart, r.blockRecords)
+	if err != nil {
+		return errors.Wrap(err, "failed to write index")
+	}
+
+	r.blockStart = r.cw.n
+	r.blockRecords = 0
+	r.gz.Reset(r.cw)
+
+	return nil
+}
+
+func (r *RotatingGzipWriter) closeFile() error {
+	if r.file == nil {
+		return nil
+	}
+
+	// If the compressor has failed, endBlock still tries to finish the
+	// member; either way both files are closed.
+	err := r.endBlock()
+	if cerr := r.index.Close(); err == nil && cerr != nil {
+		err = cerr
+	}
+	if cerr := r.file.Close(); err == nil && cerr != nil {
+		err = cerr
+	}
+	r.file, r.index, r.gz = nil, nil, nil
+
+	return err
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,49 @@
+package logshare
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// Sample rates. The API samples in steps of MinSample: a rate is rounded to
+// the nearest step, and raised to MinSample if it would round to zero.
+const (
+	// NoSampling fetches every log; it is the same as not setting Sample.
+	NoSampling = 1.0
+	// MinSample is the smallest rate the API accepts, one log in a thousand.
+	MinSample = 0.001
+)
+
+// EstimateSampledCount estimates how many of totalEstimate logs a pull with
+// the given Sample rate returns, after the rate is rounded as it is for
+// requests. Each log is sampled independently, so the actual count varies
+// around the estimate.
+func EstimateSampledCount(totalEstimate int, sample float64) int {
+	return int(math.Round(float64(totalEstimate) * effectiveSample(sample)))
+}
+
+// validateSample returns an error for a Sample rate that can't be used.
+func validateSample(sample float64) error {
+	if math.IsNaN(sample) || sample < 0 {
+		return errors.Errorf("invalid Sample %v: must be between %v and %v", sample, MinSample, NoSampling)
+	}
+
+	return nil
+}
+
+// effectiveSample returns the rate the API samples at for the given Sample:
+// rounded to a step of MinSample and clamped to [MinSample, NoSampling], with
+// zero meaning NoSampling.
+func effectiveSample(sample float64) float64 {
+	if sample <= 0 || sample >= NoSampling {
+		return NoSampling
+	}
+
+	s := math.Round(sample/MinSample) * MinSample
+	if s < MinSample {
+		s = MinSample
+	}
+
+	return s
+}
@@ -0,0 +1,89 @@
+package logshare
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+)
+
+// sampleFunc returns a recordFunc that keeps a deterministic sample of logs
+// when Options.SampleSeed is set: a log is kept if the hash of the seed and
+// its RayID falls within the sample rate, so the same seed always keeps the
+// same logs. It returns nil otherwise, leaving sampling to the API.
+func (c *Client) sampleFunc() recordFunc {
+	if !c.seeded() {
+		return nil
+	}
+
+	keep := c.sampleKeep()
+
+	return func(rec LogRecord) (LogRecord, error) {
+		ray, _ := rec["RayID"].(string)
+		if !keep([]byte(ray)) {
+			return nil, nil
+		}
+
+		return rec, nil
+	}
+}
+
+// sampleKeep returns whether a log with the given RayID is in the seeded
+// sample.
+func (c *Client) sampleKeep() func(ray []byte) bool {
+	var seed [8]byte
+	binary.BigEndian.PutUint64(seed[:], uint64(c.sampleSeed))
+	limit := uint64(c.sample * math.MaxUint64)
+	if c.sample >= 1 {
+		limit = math.MaxUint64
+	}
+
+	return func(ray []byte) bool {
+		h := fnv.New64a()
+		h.Write(seed[:])
+		h.Write(ray)
+
+		return h.Sum64() <= limit
+	}
+}
+
+// seeded reports whether logs are sampled on the client with
+// Options.SampleSeed, rather than by the API.
+func (c *Client) seeded() bool {
+	return c.sample != 0 && c.sampleSeed != 0
+}
+
+// recordLimit is a recordFunc that passes on the first limit logs that reach
+// it, then drops the rest. A limit of zero passes every log.
+type recordLimit struct {
+	limit int
+	kept  int
+}
+
+func (r *recordLimit) apply(rec LogRecord) (LogRecord, error) {
+	if r.limit <= 0 {
+		return rec, nil
+	}
+
+	if r.kept >= r.limit {
+		return nil, nil
+	}
+	r.kept++
+
+	return rec, nil
+}
+
+// recordLimiter is implemented by logWriters that can cap the number of logs
+// written, and by those that wrap one.
+type recordLimiter interface {
+	limitRecords(n int)
+}
+
+// limitRecords caps the logs written by lw to n, if it supports it. Under
+// Options.SampleSeed, the record stage does, so that a count caps the logs
+// kept by seeded sampling (and any other record-level filter) as it does
+// those sampled by the API.
+func limitRecords(lw logWriter, n int) {
+	if l, ok := lw.(recordLimiter); ok {
+		l.limitRecords(n)
+	}
+}
@@ -0,0 +1,121 @@
+package logshare
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSampleSeedCountAfterFilters(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("count") != "" {
+			t.Errorf("count sent with SampleSeed: %s", r.URL.RawQuery)
+		}
+		for i := 0; i < 400; i++ {
+			host := "a.example.com"
+			if i%2 == 0 {
+				host = "b.example.com"
+			}
+			fmt.Fprintf(w, "{\"RayID\":\"%016x\",\"ClientRequestHost\":%q}\n", i, host)
+		}
+	})
+
+	var out bytes.Buffer
+	c, srv := newTestClient(t, handler, &Options{
+		Sample:     0.5,
+		SampleSeed: 42,
+		HostFilter: "b.example.com",
+		Sequence:   true,
+		Dest:       &out,
+	})
+	defer srv.Close()
+
+	start := time.Now().Add(-time.Hour).Unix()
+	if _, err := c.GetFromTimestamp(testZoneID, start, start+60, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 10 {
+		t.Fatalf("wrote %d logs, want 10:\n%s", len(lines), out.String())
+	}
+
+	// Logs beyond the count take no sequence numbers.
+	keep := c.sampleKeep()
+	var first int
+	for i, line := range lines {
+		var rec struct {
+			RayID             string
+			ClientRequestHost string
+			Sequence          int `json:"logshare_seq"`
+		}
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatal(err)
+		}
+		if rec.ClientRequestHost != "b.example.com" || !keep([]byte(rec.RayID)) {
+			t.Errorf("wrote %s, which is filtered out", line)
+		}
+		if i == 0 {
+			first = rec.Sequence
+		}
+		if rec.Sequence != first+i {
+			t.Errorf("log %d numbered %d, want %d", i, rec.Sequence, first+i)
+		}
+	}
+}
+
+// sinkFunc is a Sink that calls a function.
+type sinkFunc func(ctx context.Context, records []LogRecord) error
+
+func (f sinkFunc) Write(ctx context.Context, records []LogRecord) error { return f(ctx, records) }
+
+func TestSampleSeedCountOnEveryPath(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("count") != "" {
+			t.Errorf("count sent with SampleSeed: %s", r.URL.RawQuery)
+		}
+		w.Write([]byte(testLogs(400)))
+	})
+
+	c, srv := newTestClient(t, handler, &Options{Sample: 0.5, SampleSeed: 42})
+	defer srv.Close()
+
+	start := time.Now().Add(-time.Hour).Unix()
+
+	t.Run("PullToSink", func(t *testing.T) {
+		n := 0
+		sink := sinkFunc(func(ctx context.Context, records []LogRecord) error {
+			n += len(records)
+			return nil
+		})
+		meta, err := c.PullToSink(context.Background(), testZoneID, start, start+60, 10, sink, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 10 {
+			t.Errorf("sank %d logs, want 10", n)
+		}
+		if meta.RequestedCount != 10 {
+			t.Errorf("RequestedCount = %d, want 10", meta.RequestedCount)
+		}
+	})
+
+	t.Run("StreamRecords", func(t *testing.T) {
+		records, errc := c.StreamRecords(context.Background(), testZoneID, start, start+60, 10)
+		n := 0
+		for range records {
+			n++
+		}
+		if err := <-errc; err != nil {
+			t.Fatal(err)
+		}
+		if n != 10 {
+			t.Errorf("streamed %d logs, want 10", n)
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package logshare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// FieldType is the JSON type of a log field.
+type FieldType string
+
+// The JSON types of log fields.
+const (
+	FieldString FieldType = "string"
+	FieldNumber FieldType = "number"
+	FieldBool   FieldType = "bool"
+	FieldObject FieldType = "object"
+	FieldArray  FieldType = "array"
+	FieldNull   FieldType = "null"
+)
+
+// ViolationKind is the kind of a SchemaViolation.
+type ViolationKind string
+
+const (
+	// ViolationWrongType is a field with a different type than expected.
+	ViolationWrongType ViolationKind = "wrong type"
+	// ViolationMissing is an expected field that a log lacks.
+	ViolationMissing ViolationKind = "missing"
+	// ViolationUnexpected is a field that is not in the schema.
+	ViolationUnexpected ViolationKind = "unexpected"
+)
+
+// SchemaViolation is a field of a log that does not match a schema.
+type SchemaViolation struct {
+	// The RayID of the log, if it has one.
+	RayID    string
+	Field    string
+	Kind     ViolationKind
+	Expected FieldType
+	Actual   FieldType
+}
+
+func (v SchemaViolation) String() string {
+	switch v.Kind {
+	case ViolationWrongType:
+		return fmt.Sprintf("%s: field %q is %s, expected %s", v.RayID, v.Field, v.Actual, v.Expected)
+	case ViolationMissing:
+		return fmt.Sprintf("%s: field %q is missing", v.RayID, v.Field)
+	}
+
+	return fmt.Sprintf("%s: field %q is unexpected", v.RayID, v.Field)
+}
+
+// ValidateAgainstSchema fetches a sample of up to sampleCount logs between
+// the start and end timestamps, and checks the fields of each against schema,
+// returning every violation. A null value matches any type. Meta.Count is the
+// number of logs checked.
+//
+// Only the fields in Options.Fields are requested, if set, so set Fields to
+// the keys of schema (or leave it unset) to avoid spurious violations.
+func (c *Client) ValidateAgainstSchema(ctx context.Context, zoneID string, start int64, end int64, sampleCount int, schema map[string]FieldType) ([]SchemaViolation, *Meta, error) {
+	if sampleCount <= 0 {
+		return nil, nil, errors.New("sampleCount must be greater than zero")
+	}
+
+	u, err := c.timestampURL(ctx, zoneID, start, end, sampleCount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sv := &schemaValidator{schema: schema}
+	meta, err := c.request(ctx, u, sv)
+	if err != nil {
+		return nil, meta, err
+	}
+
+	return sv.violations, meta, nil
+}
+
+// schemaValidator is a logWriter that checks each log against a schema.
+type schemaValidator struct {
+	schema     map[string]FieldType
+	violations []SchemaViolation
+}
+
+func (s *schemaValidator) writeLog(line []byte) error {
+	rec, err := decodeLog(line)
+	if err != nil {
+		return err
+	}
+
+	ray, _ := rec["RayID"].(string)
+	for _, field := range sortedKeys(rec) {
+		expected, ok := s.schema[field]
+		if !ok {
+			s.violations = append(s.violations, SchemaViolation{RayID: ray, Field: field, Kind: ViolationUnexpected, Actual: fieldType(rec[field])})
+			continue
+		}
+
+		if actual := fieldType(rec[field]); actual != expected && actual != FieldNull {
+			s.violations = append(s.violations, SchemaViolation{RayID: ray, Field: field, Kind: ViolationWrongType, Expected: expected, Actual: actual})
+		}
+	}
+
+	for _, field := range sortedSchema(s.schema) {
+		if _, ok := rec[field]; !ok {
+			s.violations = append(s.violations, SchemaViolation{RayID: ray, Field: field, Kind: ViolationMissing, Expected: s.schema[field]})
+		}
+	}
+
+	return nil
+}
+
+func (s *schemaValidator) close() error { return nil }
+
+// fieldType returns the type of a decoded JSON value.
+func fieldType(v interface{}) FieldType {
+	switch v.(type) {
+	case nil:
+		return FieldNull
+	case string:
+		return FieldString
+	case json.Number, float64:
+		return FieldNumber
+	case bool:
+		return FieldBool
+	case []interface{}:
+		return FieldArray
+	}
+
+	return FieldObject
+}
+
+func sortedSchema(schema map[string]FieldType) []string {
+	keys := make([]string, 0, len(schema))
+	for k := range schema {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,135 @@
+package logshare
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SchemaCache holds the available fields of each zone, as fetched by
+// FetchFields, for up to a TTL. With Options.SchemaCache set, every pull
+// checks Options.Fields against the cached fields of its zone, fetching them
+// when they are missing or stale, so that a misspelled field fails the pull
+// rather than come back empty. A SchemaCache may be shared between clients.
+//
+// The zero value is ready to use, and keeps fields for DefaultSchemaTTL.
+type SchemaCache struct {
+	ttl time.Duration
+
+	mu    sync.Mutex
+	zones map[string]cachedSchema
+}
+
+type cachedSchema struct {
+	fields  map[string]string
+	fetched time.Time
+}
+
+// DefaultSchemaTTL is how long a SchemaCache keeps each zone's fields if no
+// TTL is given.
+const DefaultSchemaTTL = time.Hour
+
+// NewSchemaCache returns a SchemaCache that keeps each zone's fields for ttl,
+// or DefaultSchemaTTL if ttl is not positive.
+func NewSchemaCache(ttl time.Duration) *SchemaCache {
+	return &SchemaCache{ttl: ttl, zones: make(map[string]cachedSchema)}
+}
+
+func (s *SchemaCache) get(zoneID string) (map[string]string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ttl := s.ttl
+	if ttl <= 0 {
+		ttl = DefaultSchemaTTL
+	}
+
+	cs, ok := s.zones[zoneID]
+	if !ok || time.Since(cs.fetched) > ttl {
+		return nil, false
+	}
+
+	return cs.fields, true
+}
+
+func (s *SchemaCache) put(zoneID string, fields map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.zones == nil {
+		s.zones = make(map[string]cachedSchema)
+	}
+	s.zones[zoneID] = cachedSchema{fields: fields, fetched: time.Now()}
+}
+
+// RefreshSchema fetches the available fields of a zone into the client's
+// SchemaCache, whether or not they are stale. It fails if no SchemaCache is
+// set.
+func (c *Client) RefreshSchema(ctx context.Context, zoneID string) error {
+	if c.schemaCache == nil {
+		return errors.New("no SchemaCache is set")
+	}
+
+	_, err := c.FetchFields(ctx, zoneID)
+	return err
+}
+
+// checkFields checks the client's fields against the cached schema of the
+// zone, if there is a SchemaCache, fetching the schema if it is stale. If
+// Fields are not set, the fields renamed by RenameFields are checked instead,
+// as New could only check them against Fields.
+func (c *Client) checkFields(ctx context.Context, zoneID string) error {
+	if c.schemaCache == nil || (len(c.fields) == 0 && len(c.renames) == 0) {
+		return nil
+	}
+
+	schema, ok := c.schemaCache.get(zoneID)
+	if !ok {
+		var err error
+		if schema, err = c.FetchFields(ctx, zoneID); err != nil {
+			return errors.Wrap(err, "failed to fetch fields to check Fields")
+		}
+	}
+
+	if len(c.fields) > 0 {
+		if unknown := unknownFields(c.fields, schema); len(unknown) > 0 {
+			return errors.Errorf("unknown fields: %s", strings.Join(unknown, "; "))
+		}
+		return nil
+	}
+
+	renamed := make([]string, 0, len(c.renames))
+	for from := range c.renames {
+		renamed = append(renamed, from)
+	}
+	sort.Strings(renamed)
+	if unknown := unknownFields(renamed, schema); len(unknown) > 0 {
+		return errors.Errorf("RenameFields: unknown fields: %s", strings.Join(unknown, "; "))
+	}
+
+	return nil
+}
+
+// unknownFields describes each of fields that is not in schema, with any
+// close matches.
+func unknownFields(fields []string, schema map[string]string) []string {
+	var unknown []string
+	for _, f := range fields {
+		if _, ok := schema[f]; ok {
+			continue
+		}
+
+		msg := fmt.Sprintf("%q", f)
+		if s := suggestFields(f, schema); len(s) > 0 {
+			msg += fmt.Sprintf(" (did you mean %s?)", strings.Join(s, ", "))
+		}
+		unknown = append(unknown, msg)
+	}
+
+	return unknown
+}
@@ -0,0 +1,106 @@
+package logshare
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// schemaServer serves the fields endpoint with RayID and EdgeStartTimestamp,
+// and a single log for any pull, counting the requests for fields.
+type schemaServer struct {
+	fetches int
+}
+
+func (s *schemaServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/fields") {
+		s.fetches++
+		w.Write([]byte(`{"RayID":"ray","EdgeStartTimestamp":"start"}`))
+		return
+	}
+
+	w.Write([]byte(testLogs(1)))
+}
+
+func TestSchemaCacheZeroValue(t *testing.T) {
+	srv := &schemaServer{}
+	cache := &SchemaCache{}
+
+	var out bytes.Buffer
+	c, ts := newTestClient(t, srv, &Options{Fields: []string{"RayID"}, SchemaCache: cache, Dest: &out})
+	defer ts.Close()
+
+	start := time.Now().Add(-time.Hour).Unix()
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetFromTimestamp(testZoneID, start, start+60, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if srv.fetches != 1 {
+		t.Errorf("fetched fields %d times, want once", srv.fetches)
+	}
+
+	bad, ts := newTestClient(t, srv, &Options{Fields: []string{"RayId"}, SchemaCache: cache, Dest: &out})
+	defer ts.Close()
+
+	_, err := bad.GetFromTimestamp(testZoneID, start, start+60, 0)
+	if err == nil || !strings.Contains(err.Error(), `unknown fields: "RayId" (did you mean RayID?)`) {
+		t.Errorf("err = %v, want unknown field RayId", err)
+	}
+}
+
+func TestSchemaCacheFetchUsesContext(t *testing.T) {
+	srv := &schemaServer{}
+	c, ts := newTestClient(t, srv, &Options{Fields: []string{"RayID"}, SchemaCache: NewSchemaCache(0)})
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now().Add(-time.Hour).Unix()
+	_, err := c.PullAndForward(ctx, testZoneID, start, start+60, 0, func(line []byte) ([]byte, error) {
+		return line, nil
+	}, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "failed to fetch fields") {
+		t.Fatalf("err = %v, want the fields fetch to fail", err)
+	}
+	if srv.fetches != 0 {
+		t.Errorf("fetched fields %d times with a cancelled context", srv.fetches)
+	}
+}
+
+func TestSchemaCacheChecksRenames(t *testing.T) {
+	srv := &schemaServer{}
+	start := time.Now().Add(-time.Hour).Unix()
+
+	c, ts := newTestClient(t, srv, &Options{
+		RenameFields: map[string]string{"RayId": "ray_id"},
+		SchemaCache:  NewSchemaCache(0),
+		Dest:         &bytes.Buffer{},
+	})
+	defer ts.Close()
+
+	_, err := c.GetFromTimestamp(testZoneID, start, start+60, 0)
+	if err == nil || !strings.Contains(err.Error(), `RenameFields: unknown fields: "RayId" (did you mean RayID?)`) {
+		t.Errorf("err = %v, want unknown renamed field RayId", err)
+	}
+
+	var out bytes.Buffer
+	ok, ts := newTestClient(t, srv, &Options{
+		RenameFields: map[string]string{"RayID": "ray_id"},
+		SchemaCache:  NewSchemaCache(0),
+		Dest:         &out,
+	})
+	defer ts.Close()
+
+	if _, err := ok.GetFromTimestamp(testZoneID, start, start+60, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"ray_id"`) {
+		t.Errorf("wrote %q, want RayID renamed to ray_id", out.String())
+	}
+}
@@ -0,0 +1,59 @@
+package logshare
+
+import (
+	"sync/atomic"
+)
+
+// DefaultSequenceField is the field that holds the sequence number of each log
+// when Options.Sequence is set and no SequenceField is given.
+const DefaultSequenceField = "logshare_seq"
+
+// sequencer hands out monotonically increasing sequence numbers. It is safe
+// for concurrent use, so that the chunks of a backfill can share one.
+type sequencer struct {
+	next         int64
+	field        string
+	countSkipped bool
+	policy       DuplicatePolicy
+}
+
+func (c *Client) newSequencer() *sequencer {
+	if !c.sequence {
+		return nil
+	}
+
+	return &sequencer{
+		next:         c.sequenceBase,
+		field:        c.sequenceField,
+		countSkipped: c.sequenceSkipped,
+		policy:       c.duplicates,
+	}
+}
+
+func (s *sequencer) take() int64 {
+	return atomic.AddInt64(&s.next, 1) - 1
+}
+
+// wrap surrounds the record funcs with the sequencer. Logs take their number
+// before any funcs run if skipped logs count, so that dropped logs leave a
+// gap; otherwise only logs that make it through every func are numbered.
+func (s *sequencer) wrap(fns []recordFunc) []recordFunc {
+	if s == nil {
+		return fns
+	}
+
+	var n int64
+	if s.countSkipped {
+		fns = append([]recordFunc{func(rec LogRecord) (LogRecord, error) {
+			n = s.take()
+			return rec, nil
+		}}, fns...)
+	}
+
+	return append(fns, func(rec LogRecord) (LogRecord, error) {
+		if !s.countSkipped {
+			n = s.take()
+		}
+		return rec, setField(rec, s.field, n, s.policy)
+	})
+}
@@ -0,0 +1,144 @@
+package logshare
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Default SinkOptions.
+const (
+	DefaultSinkBatchSize    = 500
+	DefaultSinkRetryBackoff = time.Second
+)
+
+// Sink receives decoded logs in batches, e.g. to push them to a collector
+// over gRPC or HTTP. A Sink is called from a single goroutine and must not
+// retain the slice after Write returns.
+type Sink interface {
+	Write(ctx context.Context, records []LogRecord) error
+}
+
+// SinkOptions configures how logs are delivered to a Sink.
+type SinkOptions struct {
+	// The maximum number of logs per batch. Defaults to
+	// DefaultSinkBatchSize.
+	BatchSize int
+	// The number of times to retry a batch the Sink failed to write.
+	MaxRetries int
+	// The delay before the first retry, doubled after each attempt. Defaults
+	// to DefaultSinkRetryBackoff.
+	RetryBackoff time.Duration
+}
+
+// PullToSink fetches logs between the start and end timestamps (up to 'count'
+// logs) and writes them to sink in batches.
+//
+// Batches are written synchronously as the response is read, so a slow Sink
+// applies backpressure to the API response rather than buffering logs in
+// memory. A batch that still fails after MaxRetries aborts the pull.
+func (c *Client) PullToSink(ctx context.Context, zoneID string, start int64, end int64, count int, sink Sink, opts *SinkOptions) (*Meta, error) {
+	sw := newSinkWriter(ctx, sink, opts, c.metrics)
+	meta, err := c.getFromTimestamp(ctx, zoneID, start, end, count, c.withRecordStage(sw))
+	if meta != nil {
+		meta.Batches = sw.batches
+		meta.Retries = sw.retries
+	}
+
+	return meta, err
+}
+
+// sinkWriter is a logWriter that decodes logs and delivers them to a Sink.
+type sinkWriter struct {
+	ctx     context.Context
+	sink    Sink
+	opts    SinkOptions
+	batch   []LogRecord
+	batches int
+	retries int
+	metrics MetricsRecorder
+}
+
+func newSinkWriter(ctx context.Context, sink Sink, opts *SinkOptions, metrics MetricsRecorder) *sinkWriter {
+	sw := &sinkWriter{ctx: ctx, sink: sink, metrics: metrics}
+	if opts != nil {
+		sw.opts = *opts
+	}
+
+	if sw.opts.BatchSize <= 0 {
+		sw.opts.BatchSize = DefaultSinkBatchSize
+	}
+
+	if sw.opts.RetryBackoff <= 0 {
+		sw.opts.RetryBackoff = DefaultSinkRetryBackoff
+	}
+
+	return sw
+}
+
+func (s *sinkWriter) writeLog(line []byte) error {
+	rec, err := decodeLog(line)
+	if err != nil {
+		return err
+	}
+
+	return s.writeRecord(rec)
+}
+
+func (s *sinkWriter) writeRecord(rec LogRecord) error {
+	s.batch = append(s.batch, rec)
+	if len(s.batch) < s.opts.BatchSize {
+		return nil
+	}
+
+	return s.flush()
+}
+
+func (s *sinkWriter) close() error {
+	return s.flush()
+}
+
+func (s *sinkWriter) flush() error {
+	if len(s.batch) == 0 {
+		return nil
+	}
+
+	backoff := s.opts.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := s.sink.Write(s.ctx, s.batch)
+		if err == nil {
+			break
+		}
+
+		if attempt >= s.opts.MaxRetries {
+			return errors.Wrapf(err, "sink failed to write batch %d", s.batches+1)
+		}
+
+		s.retries++
+		s.metrics.IncRetries()
+		if err := sleepContext(s.ctx, backoff); err != nil {
+			return err
+		}
+		backoff *= 2
+	}
+
+	s.batches++
+	s.batch = s.batch[:0]
+
+	return nil
+}
+
+// sleepContext waits for d, returning early with the context's error if it is
+// cancelled first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
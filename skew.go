@@ -0,0 +1,43 @@
+package logshare
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// clockSkew is the offset of the API's clock from the local clock, measured
+// from the Date header of the first response.
+type clockSkew struct {
+	mu       sync.Mutex
+	measured bool
+	offset   time.Duration
+}
+
+// observe measures the skew from resp, if it has not been measured yet, and
+// returns the current skew.
+func (s *clockSkew) observe(resp *http.Response) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.measured {
+		if date, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+			// The Date header is truncated to the second, so the skew is
+			// only known to within a second: ignore anything smaller.
+			s.offset = date.Sub(time.Now()).Round(time.Second)
+			if s.offset > -time.Second && s.offset < time.Second {
+				s.offset = 0
+			}
+			s.measured = true
+		}
+	}
+
+	return s.offset
+}
+
+func (s *clockSkew) get() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.offset
+}
@@ -0,0 +1,115 @@
+package logshare
+
+import (
+	"sync"
+)
+
+// DefaultSlowWriterBuffer is the default number of logs buffered under
+// DropOldest.
+const DefaultSlowWriterBuffer = 1000
+
+// SlowWriterPolicy controls what happens when the destination cannot keep up
+// with the API response.
+type SlowWriterPolicy int
+
+const (
+	// Block waits for the destination, applying backpressure to the API
+	// response. Nothing is lost, but a destination that stalls for long
+	// enough can cause the HTTP connection to time out. This is the default.
+	Block SlowWriterPolicy = iota
+	// DropOldest reads the response as fast as it arrives into a bounded
+	// buffer, dropping the oldest buffered logs when the buffer is full. The
+	// connection is never stalled, at the cost of losing logs; dropped logs
+	// are counted in Meta.Dropped.
+	DropOldest
+)
+
+// droppingWriter is a logWriter that hands logs to the next logWriter from a
+// separate goroutine, dropping the oldest queued logs when it falls behind.
+type droppingWriter struct {
+	next  logWriter
+	queue chan []byte
+	done  chan struct{}
+
+	mu      sync.Mutex
+	err     error
+	dropped int
+}
+
+func newDroppingWriter(next logWriter, size int) *droppingWriter {
+	if size <= 0 {
+		size = DefaultSlowWriterBuffer
+	}
+
+	d := &droppingWriter{
+		next:  next,
+		queue: make(chan []byte, size),
+		done:  make(chan struct{}),
+	}
+	go d.drain()
+
+	return d
+}
+
+func (d *droppingWriter) drain() {
+	defer close(d.done)
+
+	for line := range d.queue {
+		if err := d.next.writeLog(line); err != nil {
+			d.mu.Lock()
+			d.err = err
+			d.mu.Unlock()
+			// Keep draining so that writeLog never blocks.
+			for range d.queue {
+			}
+			return
+		}
+	}
+}
+
+func (d *droppingWriter) writeLog(line []byte) error {
+	d.mu.Lock()
+	err := d.err
+	d.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// The scanner reuses its buffer, so queued lines must be copied.
+	line = append([]byte(nil), line...)
+	for {
+		select {
+		case d.queue <- line:
+			return nil
+		default:
+		}
+
+		select {
+		case <-d.queue:
+			d.dropped++
+		default:
+		}
+	}
+}
+
+func (d *droppingWriter) close() error {
+	close(d.queue)
+	<-d.done
+
+	if d.err != nil {
+		d.next.close()
+		return d.err
+	}
+
+	return d.next.close()
+}
+
+func (d *droppingWriter) report(meta *Meta) {
+	meta.Dropped += d.dropped
+	reportTo(d.next, meta)
+}
+
+func (d *droppingWriter) markEmpty(line string) error {
+	// Nothing was queued, so the next writer is idle.
+	return markEmpty(d.next, line)
+}
@@ -0,0 +1,67 @@
+package logshare
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// StatusField is the log field StatusClassPartitionedWrite partitions by.
+const StatusField = "EdgeResponseStatus"
+
+// StatusClassUnknown is the class of logs without a valid StatusField.
+const StatusClassUnknown = "unknown"
+
+// StatusClassPartitionedWrite is a PartitionedWrite that splits logs into an
+// NDJSON file per class of EdgeResponseStatus, which must be in Fields if
+// Fields are set: dir/1xx.jsonl through dir/5xx.jsonl, and
+// dir/unknown.jsonl for logs without a valid status. Logs are written as
+// NDJSON whatever the client's output format, and without any
+// Options.EnvelopeFunc. Meta.StatusClasses counts the logs of each class.
+func (c *Client) StatusClassPartitionedWrite(ctx context.Context, zoneID string, start time.Time, end time.Time, dir string) (*Meta, error) {
+	if len(c.fields) > 0 && !hasField(c.fields, StatusField) {
+		return nil, errors.Errorf("%s must be in Fields to partition logs by status", StatusField)
+	}
+
+	nc := c.withNDJSON()
+
+	classes := make(map[string]int)
+	meta, err := nc.PartitionedWrite(ctx, zoneID, start, end, func(rec LogRecord) (string, error) {
+		class := statusClass(rec[StatusField])
+		classes[class]++
+
+		return filepath.Join(dir, class+".jsonl"), nil
+	})
+	if meta != nil && len(classes) > 0 {
+		meta.StatusClasses = classes
+	}
+
+	return meta, err
+}
+
+// statusClass returns the class of an HTTP status, such as "2xx".
+func statusClass(v interface{}) string {
+	var status int64
+	switch v := v.(type) {
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return StatusClassUnknown
+		}
+		status = n
+	case float64:
+		status = int64(v)
+	default:
+		return StatusClassUnknown
+	}
+
+	if status < 100 || status > 599 {
+		return StatusClassUnknown
+	}
+
+	return strconv.FormatInt(status/100, 10) + "xx"
+}
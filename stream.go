@@ -0,0 +1,106 @@
+package logshare
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+)
+
+// DefaultMaxLineBytes is the longest log line read when no
+// Options.MaxLineBytes is set.
+const DefaultMaxLineBytes = 1 << 20
+
+// SplitLogs is a bufio.SplitFunc for the API's newline delimited logs. It is
+// like bufio.ScanLines, dropping a trailing \r from each line, but skips blank
+// lines, and returns a final log that lacks a newline. Use it with a
+// Scanner.Buffer large enough for the longest log, or use NewLogScanner.
+func SplitLogs(data []byte, atEOF bool) (int, []byte, error) {
+	skipped := 0
+	for {
+		advance, token, err := bufio.ScanLines(data[skipped:], atEOF)
+		if err != nil || token == nil {
+			return skipped + advance, nil, err
+		}
+
+		skipped += advance
+		if len(bytes.TrimSpace(token)) > 0 {
+			return skipped, token, nil
+		}
+	}
+}
+
+// NewLogScanner returns a bufio.Scanner that reads the logs from r, as from
+// OpenFromTimestamp, one per token. Logs up to maxLineBytes long are read
+// (DefaultMaxLineBytes if it is zero or less); a longer log stops the
+// scanner with bufio.ErrTooLong. If r fails part way through a log, the
+// scanner stops with that error rather than return the partial log.
+func NewLogScanner(r io.Reader, maxLineBytes int) *bufio.Scanner {
+	if maxLineBytes <= 0 {
+		maxLineBytes = DefaultMaxLineBytes
+	}
+
+	lr := &lineReader{r: r}
+	scanner := bufio.NewScanner(lr)
+	// Leave room for the newline and a \r.
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineBytes+2)
+	scanner.Split(lr.scanLines)
+
+	return scanner
+}
+
+// LogStream is the raw stream of logs returned by OpenFromTimestamp: the
+// API's newline delimited logs, read as they are received.
+type LogStream struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+	done   chan struct{}
+	meta   *Meta
+	err    error
+}
+
+// OpenFromTimestamp fetches the logs between the start and end timestamps
+// (up to 'count' logs) like GetFromTimestamp, but returns them as a stream
+// to read, rather than writing them to the destination writer. Read the
+// stream with NewLogScanner, or a bufio.Scanner using SplitLogs. Logs are
+// newline delimited JSON whatever the output format. Record-level options
+// (such as Redactions or HostFilter) apply; without any, logs are passed
+// through as returned by the API.
+//
+// The request is made as the stream is read. If it fails, Read returns the
+// error; an empty window reads as an empty stream. The stream must be closed.
+func (c *Client) OpenFromTimestamp(ctx context.Context, zoneID string, start int64, end int64, count int) (*LogStream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+	s := &LogStream{PipeReader: pr, cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		defer cancel()
+
+		meta, err := c.getFromTimestamp(ctx, zoneID, start, end, count, c.withRecordStage(&ndjsonWriter{w: pw}))
+		if meta != nil && meta.StatusCode == http.StatusNoContent {
+			err = nil
+		}
+		s.meta, s.err = meta, err
+		pw.CloseWithError(err)
+	}()
+
+	return s, nil
+}
+
+// Close closes the stream, cancelling the request if it has not finished.
+func (s *LogStream) Close() error {
+	s.PipeReader.Close()
+	s.cancel()
+	<-s.done
+
+	return nil
+}
+
+// Meta waits for the request to finish, and returns its Meta and error. It
+// should be called once the stream has been read to the end, or closed.
+func (s *LogStream) Meta() (*Meta, error) {
+	<-s.done
+	return s.meta, s.err
+}
@@ -0,0 +1,24 @@
+package logshare
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLogStreamCloseCancels(t *testing.T) {
+	c, srv := newTestClient(t, &slowServer{delay: 10 * time.Second}, &Options{})
+	defer srv.Close()
+
+	start := time.Now().Add(-time.Hour).Unix()
+	s, err := c.OpenFromTimestamp(context.Background(), testZoneID, start, start+60, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	began := time.Now()
+	s.Close()
+	if elapsed := time.Since(began); elapsed > time.Second {
+		t.Errorf("Close took %v, want it to cancel the request", elapsed)
+	}
+}
@@ -0,0 +1,65 @@
+package logshare
+
+import (
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultSummaryField is the marker field of the summary line written by
+// Options.EmitSummary when no SummaryField is set.
+const DefaultSummaryField = "logshare_summary"
+
+// summaryWriter is implemented by logWriters that can append a summary line
+// after the logs.
+type summaryWriter interface {
+	writeSummary(line []byte) error
+}
+
+// writeSummaryTo writes a summary line to lw, if it supports it.
+func writeSummaryTo(lw logWriter, line []byte) error {
+	if sw, ok := lw.(summaryWriter); ok {
+		return sw.writeSummary(line)
+	}
+
+	return nil
+}
+
+// writeSummary writes a summary of a completed request to lw, if it supports
+// it.
+func (c *Client) writeSummary(lw logWriter, u *url.URL, meta *Meta, d time.Duration) error {
+	sw, ok := lw.(summaryWriter)
+	if !ok {
+		return nil
+	}
+
+	params := u.Query()
+	line, err := json.Marshal(map[string]interface{}{
+		c.summaryField: true,
+		"count":        meta.Count,
+		"bytes":        meta.Bytes,
+		"start":        params.Get(c.startParam()),
+		"end":          params.Get(c.endParam()),
+		"duration_ms":  int64(d / time.Millisecond),
+		"first_ray_id": meta.FirstRayID,
+		"last_ray_id":  meta.LastRayID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrap(sw.writeSummary(line), "failed to write summary")
+}
+
+// rayIDOf returns the RayID of a raw log, or an empty string if it has none.
+func rayIDOf(line []byte) string {
+	var rec struct{ RayID string }
+	json.Unmarshal(line, &rec)
+	return rec.RayID
+}
+
+func (n *ndjsonWriter) writeSummary(line []byte) error {
+	return n.writeLog(line)
+}
@@ -0,0 +1,130 @@
+package logshare
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/pkg/errors"
+)
+
+// templateFuncs are the functions available to Options.Template, in addition
+// to the text/template builtins.
+var templateFuncs = template.FuncMap{
+	// json encodes a value as JSON, for quoting strings.
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// compileTemplate parses a FormatTemplate template. Referencing a field that a
+// log does not have is an error if strict is set.
+func compileTemplate(text string, strict bool) (*template.Template, error) {
+	tmpl := template.New("log").Funcs(templateFuncs)
+	if strict {
+		tmpl = tmpl.Option("missingkey=error")
+	}
+
+	tmpl, err := tmpl.Parse(text)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid Template")
+	}
+
+	return tmpl, nil
+}
+
+// templateWriter renders each log with a template, followed by a newline.
+type templateWriter struct {
+	w      io.Writer
+	tmpl   *template.Template
+	strict bool
+	// The fields the template refers to, which are set to an empty string in
+	// logs that lack them or where they are null (unless strict).
+	fields []string
+	buf    bytes.Buffer
+}
+
+func newTemplateWriter(w io.Writer, tmpl *template.Template, strict bool) *templateWriter {
+	t := &templateWriter{w: w, tmpl: tmpl, strict: strict}
+	if !strict {
+		t.fields = templateFields(tmpl.Tree.Root, nil)
+	}
+
+	return t
+}
+
+func (t *templateWriter) writeLog(line []byte) error {
+	rec, err := decodeLog(line)
+	if err != nil {
+		return err
+	}
+
+	return t.writeRecord(rec)
+}
+
+func (t *templateWriter) writeRecord(rec LogRecord) error {
+	for _, f := range t.fields {
+		if rec[f] == nil {
+			rec[f] = ""
+		}
+	}
+
+	t.buf.Reset()
+	if err := t.tmpl.Execute(&t.buf, map[string]interface{}(rec)); err != nil {
+		return errors.Wrap(err, "failed to render log")
+	}
+	t.buf.WriteByte('\n')
+
+	_, err := t.w.Write(t.buf.Bytes())
+	return err
+}
+
+func (t *templateWriter) close() error { return nil }
+
+// templateFields appends the names of the fields referred to by node (such as
+// ClientIP in "{{.ClientIP}}") to fields.
+func templateFields(node parse.Node, fields []string) []string {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return fields
+		}
+		for _, c := range n.Nodes {
+			fields = templateFields(c, fields)
+		}
+	case *parse.ActionNode:
+		fields = templateFields(n.Pipe, fields)
+	case *parse.PipeNode:
+		if n == nil {
+			return fields
+		}
+		for _, c := range n.Cmds {
+			fields = templateFields(c, fields)
+		}
+	case *parse.CommandNode:
+		for _, a := range n.Args {
+			fields = templateFields(a, fields)
+		}
+	case *parse.FieldNode:
+		if !hasField(fields, n.Ident[0]) {
+			fields = append(fields, n.Ident[0])
+		}
+	case *parse.IfNode:
+		fields = templateFields(&n.BranchNode, fields)
+	case *parse.RangeNode:
+		fields = templateFields(&n.BranchNode, fields)
+	case *parse.WithNode:
+		fields = templateFields(&n.BranchNode, fields)
+	case *parse.BranchNode:
+		fields = templateFields(n.Pipe, fields)
+		fields = templateFields(n.List, fields)
+		fields = templateFields(n.ElseList, fields)
+	case *parse.TemplateNode:
+		fields = templateFields(n.Pipe, fields)
+	}
+
+	return fields
+}
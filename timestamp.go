@@ -0,0 +1,79 @@
+package logshare
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// TimestampField is the log field used to order logs by time.
+const TimestampField = "EdgeStartTimestamp"
+
+// recordTime returns the time held in a log field, which may be in any of the
+// API's timestamp formats: Unix seconds, Unix nanoseconds or RFC3339.
+func recordTime(rec LogRecord, field string) (time.Time, bool) {
+	switch v := rec[field].(type) {
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return time.Time{}, false
+		}
+		if n > 1e12 {
+			return time.Unix(0, n), true
+		}
+		return time.Unix(n, 0), true
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		return t, err == nil
+	}
+
+	return time.Time{}, false
+}
+
+// lineTime returns the EdgeStartTimestamp of a raw log, found without
+// decoding the rest of the log.
+func lineTime(line []byte) (time.Time, bool) {
+	v := lineValue(line, TimestampField)
+	if v == nil {
+		return time.Time{}, false
+	}
+
+	return rawTime(v)
+}
+
+// lineValue returns the raw JSON value of a top-level field of a raw log,
+// found without decoding the rest of the log, or nil if it has none. It
+// relies on the API's compact encoding, and only finds scalar values.
+func lineValue(line []byte, field string) []byte {
+	key := append(strconv.AppendQuote([]byte(nil), field), ':')
+	i := bytes.Index(line, key)
+	if i < 0 {
+		return nil
+	}
+
+	v := bytes.TrimLeft(line[i+len(key):], " ")
+	if len(v) == 0 {
+		return nil
+	}
+
+	end := bytes.IndexAny(v, ",}")
+	if v[0] == '"' {
+		end = bytes.IndexByte(v[1:], '"') + 2
+	}
+	if end <= 0 {
+		return nil
+	}
+
+	return bytes.TrimSpace(v[:end])
+}
+
+// observeTime widens the Meta's MinTimestamp and MaxTimestamp to include t.
+func (m *Meta) observeTime(t time.Time) {
+	if m.MinTimestamp.IsZero() || t.Before(m.MinTimestamp) {
+		m.MinTimestamp = t
+	}
+	if t.After(m.MaxTimestamp) {
+		m.MaxTimestamp = t
+	}
+}
@@ -0,0 +1,64 @@
+package logshare
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMetaTimestampBounds(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []string
+		logs   []string
+		min    int64
+		max    int64
+	}{
+		{
+			name: "fields unset",
+			logs: []string{testLog("a", 20), testLog("b", 10), testLog("c", 30)},
+			min:  10,
+			max:  30,
+		},
+		{
+			name:   "timestamp in fields",
+			fields: []string{"RayID", TimestampField},
+			logs:   []string{testLog("a", 20), testLog("b", 10)},
+			min:    10,
+			max:    20,
+		},
+		{
+			name:   "timestamp not in fields",
+			fields: []string{"RayID"},
+			logs:   []string{`{"RayID":"a"}`, `{"RayID":"b"}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for _, line := range tt.logs {
+					fmt.Fprintln(w, line)
+				}
+			})
+			c, ts := newTestClient(t, handler, &Options{Fields: tt.fields, Dest: &bytes.Buffer{}})
+			defer ts.Close()
+
+			start := time.Now().Add(-time.Hour).Unix()
+			meta, err := c.GetFromTimestamp(testZoneID, start, start+60, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var min, max time.Time
+			if tt.max > 0 {
+				min, max = time.Unix(tt.min, 0), time.Unix(tt.max, 0)
+			}
+			if !meta.MinTimestamp.Equal(min) || !meta.MaxTimestamp.Equal(max) {
+				t.Errorf("bounds = %v to %v, want %v to %v", meta.MinTimestamp, meta.MaxTimestamp, min, max)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package logshare
+
+import (
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestTraceTimingsConcurrentConnects(t *testing.T) {
+	meta := &Meta{}
+	trace, stop := traceTimings(meta)
+
+	// Race a failing and a successful dial, as the dialer does for IPv6 and
+	// IPv4 addresses.
+	var wg sync.WaitGroup
+	for _, addr := range []string{"[2001:db8::1]:443", "192.0.2.1:443"} {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			trace.ConnectStart("tcp", addr)
+			time.Sleep(time.Millisecond)
+			var err error
+			if addr[0] == '[' {
+				err = errors.New("network unreachable")
+			}
+			trace.ConnectDone("tcp", addr, err)
+		}(addr)
+	}
+	wg.Wait()
+
+	d := meta.ConnectDuration
+	if d < time.Millisecond {
+		t.Errorf("ConnectDuration = %v, want the successful connect's duration", d)
+	}
+
+	// A dial that outlives the request is not recorded.
+	stop()
+	trace.ConnectStart("tcp", "192.0.2.2:443")
+	trace.ConnectDone("tcp", "192.0.2.2:443", nil)
+	trace.GotFirstResponseByte()
+	if meta.ConnectDuration != d || meta.TimeToFirstByte != 0 {
+		t.Errorf("recorded timings after stop: %+v", meta)
+	}
+}
+
+func TestTraceTimings(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testLogs(1)))
+	})
+	c, srv := newTestClient(t, handler, &Options{Trace: true, Dest: ioutil.Discard})
+	defer srv.Close()
+
+	start := time.Now().Add(-time.Hour).Unix()
+	meta, err := c.GetFromTimestamp(testZoneID, start, start+60, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.ConnectDuration <= 0 || meta.TimeToFirstByte <= 0 {
+		t.Errorf("ConnectDuration = %v, TimeToFirstByte = %v, want both set", meta.ConnectDuration, meta.TimeToFirstByte)
+	}
+}
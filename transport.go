@@ -0,0 +1,120 @@
+package logshare
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Default connection settings, matching http.DefaultTransport.
+const (
+	DefaultKeepAlive       = 30 * time.Second
+	DefaultIdleConnTimeout = 90 * time.Second
+)
+
+// newHTTPClient returns an HTTP client using the transport settings in
+// options, or nil if they are all unset (so that the default client is used).
+//
+// Pulls run every minute or so pay for a new TLS handshake whenever their
+// connection has been closed as idle in between; an IdleConnTimeout longer
+// than the interval between pulls keeps the connection warm across them.
+func newHTTPClient(options *Options) *http.Client {
+	if options.Transport != nil {
+		return &http.Client{Transport: options.Transport}
+	}
+
+	if options.KeepAlive == 0 && options.IdleConnTimeout == 0 {
+		return nil
+	}
+
+	keepAlive := options.KeepAlive
+	if keepAlive == 0 {
+		keepAlive = DefaultKeepAlive
+	}
+
+	idle := options.IdleConnTimeout
+	if idle == 0 {
+		idle = DefaultIdleConnTimeout
+	}
+
+	// As http.DefaultTransport, but for the connection settings.
+	t := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: keepAlive,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       idle,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	// Every request goes to the same host, so keep as many idle connections
+	// for it as in total, rather than the default of two, for concurrent
+	// pulls (see WarmConnections).
+	t.MaxIdleConnsPerHost = t.MaxIdleConns
+
+	return &http.Client{Transport: t}
+}
+
+// WarmConnections opens n connections to the API ahead of a burst of
+// concurrent requests, such as a ConcurrentBackfill, so that the first
+// requests don't each pay for a TCP and TLS handshake. Each connection is
+// opened with a HEAD request to the API root, without credentials, and kept
+// idle in the client's pool for the later requests.
+//
+// Warming costs n requests up front, and the connections are closed again if
+// they sit idle for longer than IdleConnTimeout, so warm shortly before the
+// burst. The default HTTP client keeps only two idle connections per host;
+// set KeepAlive or IdleConnTimeout for a transport of the client's own,
+// which keeps as many as are warmed. WarmConnections does nothing when
+// Options.Transport or Options.HTTPClient is set, as the caller then
+// controls the connections.
+func (c *Client) WarmConnections(ctx context.Context, n int) error {
+	if c.customTransport || n <= 0 {
+		return nil
+	}
+
+	// The requests are made at once, so that each needs a connection of its
+	// own rather than reusing one just released.
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- c.warmConnection(ctx)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			return errors.Wrap(err, "failed to warm connection")
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) warmConnection(ctx context.Context) error {
+	req, err := http.NewRequest(http.MethodHead, c.endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+
+	return resp.Body.Close()
+}
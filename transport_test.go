@@ -0,0 +1,63 @@
+package logshare
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// BenchmarkRepeatedPulls compares repeated pulls over a kept-alive connection
+// with pulls that each pay for a new TCP and TLS handshake, as when the
+// connection is closed as idle between scheduled pulls.
+func BenchmarkRepeatedPulls(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		idle time.Duration
+	}{
+		{"warm", time.Minute},
+		{"reaped", time.Nanosecond},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			var handshakes int64
+			srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintln(w, `{"RayID":"a"}`)
+			}))
+			srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					atomic.AddInt64(&handshakes, 1)
+				}
+			}
+			srv.StartTLS()
+			defer srv.Close()
+
+			c, err := New("token", "", "", &Options{IdleConnTimeout: bm.idle, Dest: ioutil.Discard})
+			if err != nil {
+				b.Fatal(err)
+			}
+			c.endpoint = srv.URL
+			// Trust the test server's certificate.
+			c.httpClient.Transport.(*http.Transport).TLSClientConfig = srv.Client().Transport.(*http.Transport).TLSClientConfig
+
+			start := time.Now().Add(-time.Hour).Unix()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := c.GetFromTimestamp(testZoneID, start, start+60, 0); err != nil {
+					b.Fatal(err)
+				}
+				// Give an idle connection time to be closed.
+				b.StopTimer()
+				time.Sleep(time.Millisecond)
+				b.StartTimer()
+			}
+			b.StopTimer()
+			b.Logf("%d pulls, %d handshakes", b.N, atomic.LoadInt64(&handshakes))
+		})
+	}
+}
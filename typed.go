@@ -0,0 +1,87 @@
+package logshare
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Record is a decoded log with typed accessors. Each accessor reports false
+// if the field is missing or does not hold a value of that type.
+type Record LogRecord
+
+// GetString returns the value of a string field.
+func (r Record) GetString(field string) (string, bool) {
+	s, ok := r[field].(string)
+	return s, ok
+}
+
+// GetInt returns the value of an integer field. Numbers with a fractional
+// part are not integers.
+func (r Record) GetInt(field string) (int64, bool) {
+	n, ok := r[field].(json.Number)
+	if !ok {
+		return 0, false
+	}
+
+	i, err := n.Int64()
+	return i, err == nil
+}
+
+// GetFloat returns the value of a numeric field.
+func (r Record) GetFloat(field string) (float64, bool) {
+	n, ok := r[field].(json.Number)
+	if !ok {
+		return 0, false
+	}
+
+	f, err := n.Float64()
+	return f, err == nil
+}
+
+// GetBool returns the value of a boolean field.
+func (r Record) GetBool(field string) (bool, bool) {
+	b, ok := r[field].(bool)
+	return b, ok
+}
+
+// GetTime returns the value of a timestamp field, in any of the API's
+// timestamp formats (see Options.TimestampFormat).
+func (r Record) GetTime(field string) (time.Time, bool) {
+	return recordTime(LogRecord(r), field)
+}
+
+// GetTypedFromTimestamp is like GetFromTimestamp, but returns the logs as
+// Records rather than writing them to the destination. Every log is held in
+// memory, so it is best suited to small pulls.
+func (c *Client) GetTypedFromTimestamp(ctx context.Context, zoneID string, start int64, end int64, count int) ([]Record, *Meta, error) {
+	rc := &recordCollector{}
+	meta, err := c.getFromTimestamp(ctx, zoneID, start, end, count, c.withRecordStage(rc))
+	if meta != nil && meta.StatusCode == http.StatusNoContent {
+		err = nil
+	}
+
+	return rc.records, meta, err
+}
+
+// recordCollector is a logWriter that keeps every log as a Record.
+type recordCollector struct {
+	records []Record
+}
+
+func (r *recordCollector) writeLog(line []byte) error {
+	rec, err := decodeLog(line)
+	if err != nil {
+		return err
+	}
+
+	return r.writeRecord(rec)
+}
+
+func (r *recordCollector) writeRecord(rec LogRecord) error {
+	r.records = append(r.records, Record(rec))
+	return nil
+}
+
+func (r *recordCollector) close() error { return nil }
@@ -0,0 +1,75 @@
+package logshare
+
+import (
+	"bytes"
+	"context"
+	"io"
+)
+
+// wallClockKey is the context key under which withWallClock keeps the
+// caller's own context.
+type wallClockKey struct{}
+
+// withWallClock returns a context that ends after Options.MaxWallClock, if it
+// is set. A context that already has the deadline is returned as-is, so that
+// a public call that makes several requests, such as a paginated pull, is
+// bounded as a whole rather than per request.
+func (c *Client) withWallClock(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.maxWallClock <= 0 || ctx.Value(wallClockKey{}) != nil {
+		return ctx, func() {}
+	}
+
+	rctx, cancel := context.WithTimeout(ctx, c.maxWallClock)
+	return context.WithValue(rctx, wallClockKey{}, ctx), cancel
+}
+
+// cutShort reports whether ctx, from withWallClock, ended because
+// Options.MaxWallClock was reached, rather than because the caller's own
+// context ended.
+func cutShort(ctx context.Context) bool {
+	parent, ok := ctx.Value(wallClockKey{}).(context.Context)
+	return ok && parent.Err() == nil && ctx.Err() == context.DeadlineExceeded
+}
+
+// partial turns a request cut short by Options.MaxWallClock into a partial
+// success: the logs read before the deadline have been written, so Meta.Partial
+// is set rather than an error returned. Errors from anything else, including
+// the caller's own context, are returned as-is.
+func partial(ctx context.Context, meta *Meta, err error) (*Meta, error) {
+	if err == nil || !cutShort(ctx) {
+		return meta, err
+	}
+
+	if meta == nil {
+		meta = &Meta{}
+	}
+	meta.Partial = true
+
+	return meta, nil
+}
+
+// lineReader records the error that ended a read, so that a log cut short by
+// it can be told apart from a final log without a trailing newline.
+type lineReader struct {
+	r   io.Reader
+	err error
+}
+
+func (l *lineReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	if err != nil && err != io.EOF {
+		l.err = err
+	}
+
+	return n, err
+}
+
+// scanLines is like SplitLogs, but fails rather than return a partial last
+// line when the read failed part way through it.
+func (l *lineReader) scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && l.err != nil && bytes.IndexByte(data, '\n') < 0 {
+		return 0, nil, l.err
+	}
+
+	return SplitLogs(data, atEOF)
+}
@@ -0,0 +1,121 @@
+package logshare
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowServer responds to every request with a single log after delay, or
+// gives up once the request is cancelled.
+type slowServer struct {
+	delay time.Duration
+
+	mu       sync.Mutex
+	requests int
+}
+
+func (s *slowServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests++
+	n := s.requests
+	s.mu.Unlock()
+
+	select {
+	case <-time.After(s.delay):
+		fmt.Fprintf(w, "{\"RayID\":\"%016x\"}\n", n)
+	case <-r.Context().Done():
+	}
+}
+
+const (
+	testWallClock = 250 * time.Millisecond
+	testDelay     = 100 * time.Millisecond
+)
+
+func TestMaxWallClockPaginated(t *testing.T) {
+	var out bytes.Buffer
+	c, srv := newTestClient(t, &slowServer{delay: testDelay}, &Options{MaxCount: 1, MaxWallClock: testWallClock, Dest: &out})
+	defer srv.Close()
+
+	began := time.Now()
+	start := time.Now().Add(-time.Hour).Unix()
+	meta, err := c.GetFromTimestamp(testZoneID, start, start+60, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(began); elapsed > 2*testWallClock {
+		t.Errorf("took %v, want about MaxWallClock (%v)", elapsed, testWallClock)
+	}
+	if !meta.Partial {
+		t.Error("Partial = false, want true")
+	}
+	if meta.Count >= 10 || meta.Count != bytes.Count(out.Bytes(), []byte("\n")) {
+		t.Errorf("Count = %d, wrote %q", meta.Count, out.String())
+	}
+}
+
+// memCheckpointer is a Checkpointer that keeps cursors in memory.
+type memCheckpointer map[string]time.Time
+
+func (m memCheckpointer) LoadCheckpoint(zoneID string) (time.Time, bool, error) {
+	t, ok := m[zoneID]
+	return t, ok, nil
+}
+
+func (m memCheckpointer) SaveCheckpoint(zoneID string, cursor time.Time) error {
+	m[zoneID] = cursor
+	return nil
+}
+
+func TestMaxWallClockIncremental(t *testing.T) {
+	c, srv := newTestClient(t, &slowServer{delay: testDelay}, &Options{MaxWallClock: testWallClock})
+	defer srv.Close()
+
+	cursor := c.availableEnd().Add(-10 * time.Minute)
+	cp := memCheckpointer{testZoneID: cursor}
+
+	var out bytes.Buffer
+	meta, err := c.Incremental(context.Background(), testZoneID, cp, time.Minute, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !meta.Partial {
+		t.Error("Partial = false, want true")
+	}
+
+	// Every checkpointed window has been written, and the one cut short has
+	// not been checkpointed.
+	windows := int(cp[testZoneID].Sub(cursor) / time.Minute)
+	if windows >= 10 || windows != bytes.Count(out.Bytes(), []byte("\n")) {
+		t.Errorf("checkpointed %d windows, wrote %q", windows, out.String())
+	}
+}
+
+func TestMaxWallClockBackfill(t *testing.T) {
+	var out bytes.Buffer
+	c, srv := newTestClient(t, &slowServer{delay: testDelay}, &Options{MaxWallClock: testWallClock, Dest: &out})
+	defer srv.Close()
+
+	end := time.Now().Add(-time.Hour).Truncate(time.Minute)
+	meta, err := c.ConcurrentBackfill(context.Background(), testZoneID, end.Add(-10*time.Minute), end, &BackfillOptions{ChunkSize: time.Minute, Concurrency: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !meta.Partial {
+		t.Error("Partial = false, want true")
+	}
+
+	// Only whole chunks are written, and each is listed to resume from.
+	fetched := len(meta.FetchedWindows)
+	if fetched >= 10 || fetched != bytes.Count(out.Bytes(), []byte("\n")) || meta.Count != fetched {
+		t.Errorf("fetched %d chunks, Count = %d, wrote %q", fetched, meta.Count, out.String())
+	}
+}
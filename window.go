@@ -0,0 +1,52 @@
+package logshare
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultAvailabilityLag is how far behind the current time logs are assumed
+// to be available from the API. Requesting more recent logs may return
+// incomplete results.
+const DefaultAvailabilityLag = time.Minute
+
+// WindowLast returns the window covering the last d of available logs: from
+// now - lag - d to now - lag, where lag is DefaultAvailabilityLag. Both ends
+// are truncated to the second.
+func WindowLast(d time.Duration) (start time.Time, end time.Time) {
+	return windowLast(time.Now(), DefaultAvailabilityLag, d)
+}
+
+// WindowLast is like the package-level WindowLast, but uses the client's
+// Options.AvailabilityLag.
+func (c *Client) WindowLast(d time.Duration) (start time.Time, end time.Time) {
+	end = c.availableEnd()
+	return end.Add(-d), end
+}
+
+// GetLast fetches the logs from the last d of available logs (up to 'count'
+// logs). See WindowLast.
+func (c *Client) GetLast(zoneID string, d time.Duration, count int) (*Meta, error) {
+	start, end := c.WindowLast(d)
+	return c.getFromTimestamp(context.Background(), zoneID, start.Unix(), end.Unix(), count, c.newLogWriter(c.dest))
+}
+
+// availableEnd returns the latest time logs are available up to.
+func (c *Client) availableEnd() time.Time {
+	return c.now().Add(-c.availabilityLag).Truncate(time.Second)
+}
+
+// now returns the current time, corrected for clock skew if
+// Options.DetectClockSkew is set.
+func (c *Client) now() time.Time {
+	if c.detectSkew {
+		return time.Now().Add(c.skew.get())
+	}
+
+	return time.Now()
+}
+
+func windowLast(now time.Time, lag time.Duration, d time.Duration) (time.Time, time.Time) {
+	end := now.Add(-lag).Truncate(time.Second)
+	return end.Add(-d), end
+}
@@ -0,0 +1,79 @@
+package logshare
+
+import (
+	"strings"
+	"sync"
+
+	cloudflare "github.com/cloudflare/cloudflare-go"
+	"github.com/pkg/errors"
+)
+
+// ZoneResolver looks up the ID of the zone with the given name.
+type ZoneResolver func(name string) (string, error)
+
+// zoneCache holds the zone IDs resolved by a client.
+type zoneCache struct {
+	mu  sync.Mutex
+	ids map[string]string
+}
+
+// resolveZone returns zone unchanged if it is a zone ID, and otherwise
+// resolves it as a zone name. Names are resolved once per client.
+func (c *Client) resolveZone(zone string) (string, error) {
+	if isZoneID(zone) {
+		return zone, nil
+	}
+
+	if zone == "" {
+		return "", errors.New("zone cannot be empty")
+	}
+
+	c.zones.mu.Lock()
+	defer c.zones.mu.Unlock()
+
+	if id, ok := c.zones.ids[zone]; ok {
+		return id, nil
+	}
+
+	resolve := c.zoneResolver
+	if resolve == nil {
+		resolve = c.lookupZone
+	}
+
+	id, err := resolve(zone)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to resolve zone %q", zone)
+	}
+
+	if c.zones.ids == nil {
+		c.zones.ids = make(map[string]string)
+	}
+	c.zones.ids[zone] = id
+
+	return id, nil
+}
+
+// lookupZone is the default ZoneResolver, which finds the zone by name with
+// the Cloudflare API using the client's credentials.
+func (c *Client) lookupZone(name string) (string, error) {
+	token := c.apiToken
+	if c.auth != AuthAPIToken {
+		token = ""
+	}
+
+	cf, err := cloudflare.New(token, c.apiKey, c.apiEmail, cloudflare.HTTPClient(c.httpClient))
+	if err != nil {
+		return "", err
+	}
+
+	return cf.ZoneIDByName(name)
+}
+
+// isZoneID reports whether zone looks like a zone ID: 32 hex digits.
+func isZoneID(zone string) bool {
+	if len(zone) != 32 {
+		return false
+	}
+
+	return strings.Trim(strings.ToLower(zone), "0123456789abcdef") == ""
+}